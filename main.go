@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
-	"github.com/eumel8/clustercheck/pkg/fluxcheck"
+	"github.com/eumel8/clustercheck/pkg/capicheck"
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"github.com/eumel8/clustercheck/pkg/common"
 	"github.com/eumel8/clustercheck/pkg/gatecheck"
 	"github.com/eumel8/clustercheck/pkg/monitoringcheck"
-	"github.com/eumel8/clustercheck/pkg/podcheck"
+	"github.com/eumel8/clustercheck/pkg/report"
 )
 
 func main() {
@@ -17,26 +22,329 @@ func main() {
 	checkPods := flag.Bool("check-pods", false, "check if all pods are in Running or Succeeded state")
 	checkFlux := flag.Bool("check-flux", false, "check if all Flux HelmReleases and Kustomizations are Ready")
 	gateCheck := flag.Bool("gate-check", false, "comprehensive cluster health check for quality gate validation")
+	checks := flag.String("checks", "", "comma-separated list of checks to run in gate check mode (default: "+strings.Join(checker.Names(), ",")+", opt-in: "+strings.Join(checker.OptInNames(), ",")+")")
+	fleet := flag.String("fleet", "", "comma-separated list or globs of kubeconfig contexts to gate check in fleet mode, * for all contexts")
+	fleetParallelism := flag.Int("fleet-parallel", gatecheck.DefaultFleetParallelism, "maximum number of clusters checked concurrently in fleet mode")
+	capi := flag.Bool("capi", false, "gate check the workload clusters of the Cluster API management cluster of the kube context in fleet mode")
+	capiNamespace := flag.String("capi-namespace", "", "namespace of the Cluster API clusters and objects (empty for all namespaces)")
+	capiSelector := flag.String("capi-selector", "", "label selector of the Cluster API clusters, the capi check is restricted to the objects of these clusters")
+	capiStuckTimeout := flag.Duration("capi-stuck-timeout", capicheck.DefaultStuckTimeout, "duration after which a Cluster API object in transition, e.g. a machine Provisioning or a MachineDeployment ScalingUp, is reported as stuck")
 	namespace := flag.String("namespace", "", "namespace to check resources (empty for all namespaces)")
-	debug := flag.Bool("debug", false, "enable debug output for API requests and responses")
+	kubeconfig := flag.String("kubeconfig", "", "path of the kubeconfig file (default the files of $KUBECONFIG or ~/.kube/config)")
+	kubeContext := flag.String("context", "", "kubeconfig context to use (default the current context)")
+	kubeCluster := flag.String("cluster", "", "kubeconfig cluster to use (default the cluster of the context)")
+	kubeUser := flag.String("user", "", "kubeconfig user to use (default the user of the context)")
+	as := flag.String("as", "", "user to impersonate for the Kubernetes API requests")
+	clusterName := flag.String("cluster-name", "", "name of the cluster shown and used for the cluster label instead of the kube context (default $CLUSTER_NAME)")
+	clusterNameConfigMap := flag.String("cluster-name-configmap", "", "ConfigMap namespace/name[:key] holding the cluster name, key defaults to "+common.DefaultClusterNameKey+" (default $CLUSTER_NAME_CONFIGMAP)")
+	configFile := flag.String("config", "", "YAML file with custom Prometheus checks (default $CLUSTERCHECK_CONFIG)")
+	timeout := flag.Duration("timeout", 0, "timeout of the whole run, e.g. 5m (default no timeout)")
+	checkTimeout := flag.Duration("check-timeout", checker.DefaultCheckTimeout, "timeout of a single check or Prometheus query")
+	retries := flag.Int("retries", checker.DefaultRetries, "number of retries of transient Prometheus and Kubernetes API errors")
+	retryBackoff := flag.Duration("retry-backoff", checker.DefaultRetryBackoff, "initial delay between retries, doubled for every retry")
+	parallelism := flag.Int("parallel", checker.DefaultParallelism, "maximum number of concurrent Prometheus queries")
+	caFile := flag.String("prometheus-ca-file", "", "PEM CA bundle to verify the Prometheus server certificate (default $PROMETHEUS_CA_FILE)")
+	certFile := flag.String("prometheus-cert-file", "", "PEM client certificate for mutual TLS with Prometheus (default $PROMETHEUS_CERT_FILE)")
+	keyFile := flag.String("prometheus-key-file", "", "PEM client key for mutual TLS with Prometheus (default $PROMETHEUS_KEY_FILE)")
+	serverName := flag.String("prometheus-server-name", "", "server name to verify the Prometheus certificate against instead of the URL host")
+	insecure := flag.Bool("insecure-skip-tls-verify", false, "skip verification of the Prometheus server certificate (insecure)")
+	strategy := flag.String("prometheus-strategy", monitoringcheck.StrategyFailover, "strategy of several Prometheus URLs: failover to the next URL on errors, or compare the results of all URLs")
+	serviceProxy := flag.Bool("service-proxy", false, "query Prometheus through the API server service proxy with the kubeconfig credentials")
+	portForward := flag.Bool("port-forward", false, "query Prometheus through a temporary port-forward to a pod of the Prometheus service")
+	prometheusService := flag.String("prometheus-service", "", "Prometheus service namespace/name[:port] for the service proxy or port-forward (default discovered by -prometheus-selector)")
+	prometheusSelector := flag.String("prometheus-selector", monitoringcheck.DefaultPrometheusSelector, "label selector to discover the Prometheus service for the service proxy or port-forward")
+	clusterLabel := flag.String("cluster-label", "", "cluster label of the Prometheus queries (default $CLUSTER, the cluster map, the kubeconfig extension or the kube context)")
+	shortClusterLabel := flag.String("short-cluster-label", "", "short cluster label of the Cluster API queries (default the cluster map, the kubeconfig extension or the kube context)")
+	clusterMap := flag.String("cluster-map", "", "YAML file mapping kube contexts and API server URLs to cluster labels (default $CLUSTERCHECK_CLUSTER_MAP)")
+	clusterLookup := flag.Bool("cluster-lookup", false, "look up the cluster label in Prometheus by the API server address of the kube context")
+	debug := flag.Bool("debug", false, "enable debug output for API requests and responses on stderr")
+	output := flag.String("output", "text", "output format: text or json")
+	junit := flag.String("junit", "", "write a JUnit XML report of the checks to the given file")
+	markdown := flag.String("markdown", "", "write a Markdown summary of the checks to the given file")
+	htmlReport := flag.String("html", "", "write a self-contained HTML report of the checks to the given file")
+	stepSummary := flag.Bool("github-step-summary", false, "append a Markdown summary of the checks to $GITHUB_STEP_SUMMARY")
 	flag.Parse()
 
-	if *gateCheck {
-		_, err := gatecheck.GateCheck(*namespace, *bitwarden, *fqdn, *debug)
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "Unknown output format %q\n", *output)
+		os.Exit(2)
+	}
+	if *timeout < 0 || *checkTimeout <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid timeout, must be a positive duration\n")
+		os.Exit(2)
+	}
+	if *retries < 0 || *retryBackoff <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid retries, must not be negative with a positive backoff\n")
+		os.Exit(2)
+	}
+	if *parallelism < 1 {
+		fmt.Fprintf(os.Stderr, "Invalid parallelism %d, must be at least 1\n", *parallelism)
+		os.Exit(2)
+	}
+	if *capiStuckTimeout <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid Cluster API stuck timeout, must be a positive duration\n")
+		os.Exit(2)
+	}
+	if *strategy != monitoringcheck.StrategyFailover && *strategy != monitoringcheck.StrategyCompare {
+		fmt.Fprintf(os.Stderr, "Unknown Prometheus strategy %q\n", *strategy)
+		os.Exit(2)
+	}
+	if *portForward && *serviceProxy {
+		fmt.Fprintf(os.Stderr, "-port-forward and -service-proxy are mutually exclusive\n")
+		os.Exit(2)
+	}
+	if *prometheusService != "" && !*portForward && !*serviceProxy {
+		fmt.Fprintf(os.Stderr, "-prometheus-service requires -service-proxy or -port-forward\n")
+		os.Exit(2)
+	}
+	if *clusterName == "" {
+		*clusterName = os.Getenv("CLUSTER_NAME")
+	}
+	if *clusterNameConfigMap == "" {
+		*clusterNameConfigMap = os.Getenv("CLUSTER_NAME_CONFIGMAP")
+	}
+	if *clusterNameConfigMap != "" {
+		if _, _, _, err := common.ParseConfigMapRef(*clusterNameConfigMap); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(2)
+		}
+	}
+	if *fleet != "" && *capi {
+		fmt.Fprintf(os.Stderr, "-fleet and -capi are mutually exclusive\n")
+		os.Exit(2)
+	}
+	if *fleet != "" || *capi {
+		if *fleetParallelism < 1 {
+			fmt.Fprintf(os.Stderr, "Invalid fleet parallelism %d, must be at least 1\n", *fleetParallelism)
+			os.Exit(2)
+		}
+		// the kube context selects the management cluster of -capi
+		if (*fleet != "" && (*kubeContext != "" || *kubeCluster != "")) || *clusterName != "" || *clusterLabel != "" || *shortClusterLabel != "" {
+			fmt.Fprintf(os.Stderr, "-fleet and -capi check several clusters, -context, -cluster, -cluster-name, -cluster-label and -short-cluster-label are not supported\n")
+			os.Exit(2)
+		}
+		if *junit != "" || *htmlReport != "" {
+			fmt.Fprintf(os.Stderr, "-junit and -html are not supported with -fleet and -capi\n")
+			os.Exit(2)
+		}
+	}
+	if *prometheusService != "" {
+		if _, err := monitoringcheck.ParseServiceRef(*prometheusService); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(2)
+		}
+	}
+	out := outputs{format: *output, junit: *junit, markdown: *markdown, html: *htmlReport}
+	if *stepSummary {
+		out.stepSummary = os.Getenv("GITHUB_STEP_SUMMARY")
+		if out.stepSummary == "" {
+			fmt.Fprintf(os.Stderr, "GITHUB_STEP_SUMMARY is not set\n")
+			os.Exit(2)
+		}
+	}
+
+	if *configFile == "" {
+		*configFile = os.Getenv("CLUSTERCHECK_CONFIG")
+	}
+
+	if *clusterMap == "" {
+		*clusterMap = os.Getenv("CLUSTERCHECK_CLUSTER_MAP")
+	}
+
+	if *caFile == "" {
+		*caFile = os.Getenv("PROMETHEUS_CA_FILE")
+	}
+	if *certFile == "" {
+		*certFile = os.Getenv("PROMETHEUS_CERT_FILE")
+	}
+	if *keyFile == "" {
+		*keyFile = os.Getenv("PROMETHEUS_KEY_FILE")
+	}
+
+	opts := checker.Options{
+		Namespace:    *namespace,
+		Bitwarden:    *bitwarden,
+		FQDN:         *fqdn,
+		Debug:        *debug,
+		ConfigFile:   *configFile,
+		Parallelism:  *parallelism,
+		CheckTimeout: *checkTimeout,
+		Retries:      *retries,
+		RetryBackoff: *retryBackoff,
+		PrometheusTLS: checker.TLSOptions{
+			CAFile:             *caFile,
+			CertFile:           *certFile,
+			KeyFile:            *keyFile,
+			ServerName:         *serverName,
+			InsecureSkipVerify: *insecure,
+		},
+		ServiceProxy:       *serviceProxy,
+		PrometheusService:  *prometheusService,
+		PrometheusSelector: *prometheusSelector,
+		PortForward:        *portForward,
+		PrometheusStrategy: *strategy,
+		ClusterLabel:       *clusterLabel,
+		ShortClusterLabel:  *shortClusterLabel,
+		ClusterMap:         *clusterMap,
+		ClusterLookup:      *clusterLookup,
+		Kube: checker.KubeOptions{
+			Kubeconfig: *kubeconfig,
+			Context:    *kubeContext,
+			Cluster:    *kubeCluster,
+			User:       *kubeUser,
+			As:         *as,
+
+			ClusterName:          *clusterName,
+			ClusterNameConfigMap: *clusterNameConfigMap,
+		},
+		CAPINamespace:    *capiNamespace,
+		CAPISelector:     *capiSelector,
+		CAPIStuckTimeout: *capiStuckTimeout,
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	var names []string
+	for _, name := range strings.Split(*checks, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	// fail on unknown check names before anything is run or reported
+	if _, err := checker.Select(checker.Options{}, names...); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -checks: %v\n", err)
+		os.Exit(2)
+	}
+
+	if *fleet != "" {
+		contexts, err := common.MatchContexts(opts.Kube, strings.Split(*fleet, ","))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to select fleet contexts: %v\n", err)
+			os.Exit(2)
+		}
+		res, err := gatecheck.RunFleet(ctx, opts, contexts, *fleetParallelism, names...)
+		out.renderFleet(res)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Fleet check failed: %v\n", err)
+			os.Exit(1)
+		}
+	} else if *capi {
+		res, err := gatecheck.RunCAPI(ctx, opts, *fleetParallelism, names...)
+		if res == nil {
+			fmt.Fprintf(os.Stderr, "Cluster API check failed: %v\n", err)
+			os.Exit(1)
+		}
+		out.renderFleet(res)
 		if err != nil {
+			fmt.Fprintf(os.Stderr, "Fleet check failed: %v\n", err)
+			os.Exit(1)
+		}
+	} else if *gateCheck {
+		res, err := gatecheck.Run(ctx, opts, names...)
+		out.render(res, true)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Gate check failed: %v\n", err)
 			os.Exit(1)
 		}
 	} else if *checkPods {
-		if err := podcheck.CheckPods(*namespace, *debug); err != nil {
-			fmt.Fprintf(os.Stderr, "Pod check failed: %v\n", err)
+		res, _ := gatecheck.Run(ctx, opts, "pods")
+		out.render(res, false)
+		if res.FailedChecks > 0 {
+			fmt.Fprintf(os.Stderr, "Pod check failed: %s\n", failures(res))
 			os.Exit(1)
 		}
 	} else if *checkFlux {
-		if err := fluxcheck.CheckFlux(*namespace, *debug); err != nil {
-			fmt.Fprintf(os.Stderr, "Flux check failed: %v\n", err)
+		res, _ := gatecheck.Run(ctx, opts, "flux")
+		out.render(res, false)
+		if res.FailedChecks > 0 {
+			fmt.Fprintf(os.Stderr, "Flux check failed: %s\n", failures(res))
 			os.Exit(1)
 		}
 	} else {
-		monitoringcheck.Run(*bitwarden, *fqdn, *debug)
+		res, _ := gatecheck.Run(ctx, opts, "prometheus")
+		out.render(res, false)
+	}
+}
+
+// outputs holds the requested output format and report files
+type outputs struct {
+	format      string
+	junit       string
+	markdown    string
+	html        string
+	stepSummary string
+}
+
+// render writes the result of a run to stdout in the requested output format
+// and to the requested report files
+func (o outputs) render(res *gatecheck.GateCheckResult, gate bool) {
+	writeReport(o.junit, false, res, report.JUnit)
+	writeReport(o.markdown, false, res, report.Markdown)
+	writeReport(o.stepSummary, true, res, report.Markdown)
+	writeReport(o.html, false, res, report.HTML)
+
+	if o.format == "json" {
+		if err := report.JSON(os.Stdout, res); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write JSON output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	report.Text(os.Stdout, res, gate)
+}
+
+// renderFleet writes the result of a fleet run to stdout in the requested
+// output format and to the requested Markdown reports
+func (o outputs) renderFleet(res *gatecheck.FleetResult) {
+	writeReport(o.markdown, false, res, report.FleetMarkdown)
+	writeReport(o.stepSummary, true, res, report.FleetMarkdown)
+
+	if o.format == "json" {
+		if err := report.FleetJSON(os.Stdout, res); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write JSON output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	report.FleetText(os.Stdout, res)
+}
+
+// writeReport writes a report of the run to the given file, if set. With
+// appendFile set the report is appended to an existing file.
+func writeReport[R any](path string, appendFile bool, res R, write func(io.Writer, R) error) {
+	if path == "" {
+		return
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendFile {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create report %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	if err := write(f, res); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write report %s: %v\n", path, err)
+		os.Exit(1)
+	}
+}
+
+// failures returns the messages of all failed check results
+func failures(res *gatecheck.GateCheckResult) string {
+	messages := []string{}
+	for _, check := range res.CheckResults {
+		if !check.Passed {
+			messages = append(messages, check.Message)
+		}
 	}
+	return strings.Join(messages, "; ")
 }
@@ -0,0 +1,354 @@
+package capicheck
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// the check is opt-in, as the Cluster API objects are forbidden for most
+// callers on a management cluster, and it only applies to management clusters
+func init() {
+	checker.RegisterOptIn("capi", 40, NewChecker)
+}
+
+// ControlPlaneGroup is the API group of the kubeadm control plane provider
+const ControlPlaneGroup = "controlplane.cluster.x-k8s.io"
+
+// DefaultStuckTimeout is the default duration after which an object in
+// transition, e.g. a Provisioning machine or a ScalingUp MachineDeployment,
+// is reported as stuck
+const DefaultStuckTimeout = 30 * time.Minute
+
+// ClusterNameLabel is the label of the Cluster API objects holding the cluster name
+const ClusterNameLabel = "cluster.x-k8s.io/cluster-name"
+
+// objectKind is a Cluster API object kind checked by the Checker
+type objectKind struct {
+	Kind     string
+	Group    string
+	Resource string
+}
+
+// objectKinds are the checked kinds in check order
+var objectKinds = []objectKind{
+	{"Cluster", Group, "clusters"},
+	{"KubeadmControlPlane", ControlPlaneGroup, "kubeadmcontrolplanes"},
+	{"MachineDeployment", Group, "machinedeployments"},
+	{"MachineSet", Group, "machinesets"},
+	{"Machine", Group, "machines"},
+}
+
+// Checker runs the Cluster API objects check of a management cluster as a
+// registered checker
+type Checker struct {
+	namespace    string
+	selector     string
+	stuckTimeout time.Duration
+	debug        bool
+	timeout      time.Duration
+	retry        checker.Retry
+	kube         checker.KubeOptions
+}
+
+// NewChecker creates a Cluster API objects Checker
+func NewChecker(opts checker.Options) checker.Checker {
+	stuckTimeout := opts.CAPIStuckTimeout
+	if stuckTimeout <= 0 {
+		stuckTimeout = DefaultStuckTimeout
+	}
+	return &Checker{
+		namespace:    opts.CAPINamespace,
+		selector:     opts.CAPISelector,
+		stuckTimeout: stuckTimeout,
+		debug:        opts.Debug,
+		timeout:      opts.Timeout(),
+		retry:        opts.Retry(),
+		kube:         opts.Kube,
+	}
+}
+
+// Name returns the display name of the check
+func (c *Checker) Name() string {
+	return "Cluster API Objects"
+}
+
+// Category returns the check category
+func (c *Checker) Category() string {
+	return checker.CategoryCAPI
+}
+
+// Run checks the Cluster API objects and returns a result per kind. A failed
+// result is returned if the cluster is no Cluster API management cluster,
+// kinds of providers which are not installed are skipped.
+func (c *Checker) Run(ctx context.Context) []checker.Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	management, err := NewManagement(c.kube, c.retry, c.debug)
+	if err != nil {
+		return []checker.Result{c.failed(ctx, objectKinds[0], err)}
+	}
+
+	results := []checker.Result{}
+	// the objects of other kinds are restricted to the selected clusters
+	var clusters map[string]bool
+	for _, kind := range objectKinds {
+		selector := ""
+		if kind.Kind == "Cluster" {
+			selector = c.selector
+		}
+		items, err := management.List(ctx, kind.Group, kind.Resource, c.namespace, selector)
+		if IsNotServed(err) {
+			// the check is selected explicitly, so it fails without Cluster API
+			if kind.Kind == "Cluster" {
+				return []checker.Result{c.failed(ctx, kind, fmt.Errorf("Cluster API is not available: %v", err))}
+			}
+			continue
+		}
+		if err != nil {
+			results = append(results, c.failed(ctx, kind, err))
+			continue
+		}
+
+		if kind.Kind == "Cluster" && c.selector != "" {
+			clusters = map[string]bool{}
+			for _, item := range items {
+				clusters[item.GetNamespace()+"/"+item.GetName()] = true
+			}
+		}
+		results = append(results, c.check(kind, items, clusters, time.Now()))
+	}
+	return results
+}
+
+// failed returns the result of a kind which could not be listed
+func (c *Checker) failed(ctx context.Context, kind objectKind, err error) checker.Result {
+	return checker.Result{
+		Name:     "CAPI " + kind.Kind + "s",
+		Category: checker.CategoryCAPI,
+		Target:   c.namespace,
+		TimedOut: checker.IsTimeout(ctx, err),
+		Message:  err.Error(),
+		Findings: []checker.Finding{},
+	}
+}
+
+// check returns the result of the objects of a kind. With clusters set only
+// the objects of these clusters are checked.
+func (c *Checker) check(kind objectKind, items []unstructured.Unstructured, clusters map[string]bool, now time.Time) checker.Result {
+	result := checker.Result{
+		Name:     "CAPI " + kind.Kind + "s",
+		Category: checker.CategoryCAPI,
+		Target:   c.namespace,
+		Findings: []checker.Finding{},
+	}
+
+	failed := 0
+	for _, item := range items {
+		if clusters != nil && !clusters[item.GetNamespace()+"/"+clusterName(item)] {
+			continue
+		}
+
+		var finding checker.Finding
+		switch kind.Kind {
+		case "Cluster":
+			finding = workloadCluster(item).Result().Findings[0]
+		case "Machine":
+			finding = machineFinding(item, c.stuckTimeout, now)
+		default:
+			finding = replicasFinding(item, c.stuckTimeout, now)
+		}
+		finding.Kind = kind.Kind
+		if !finding.Healthy {
+			failed++
+		}
+		result.Findings = append(result.Findings, finding)
+	}
+
+	switch {
+	case len(result.Findings) == 0:
+		result.Passed = true
+		result.Message = fmt.Sprintf("No %ss found", kind.Kind)
+	case failed > 0:
+		result.Message = fmt.Sprintf("%d of %d %ss not healthy", failed, len(result.Findings), kind.Kind)
+	default:
+		result.Passed = true
+		result.Message = fmt.Sprintf("All %d %ss are healthy", len(result.Findings), kind.Kind)
+	}
+	return result
+}
+
+// clusterName returns the name of the cluster of a Cluster API object: the
+// object itself for a Cluster, spec.clusterName, the cluster name label or
+// the owning Cluster
+func clusterName(item unstructured.Unstructured) string {
+	if item.GetKind() == "Cluster" {
+		return item.GetName()
+	}
+	if name, _, _ := unstructured.NestedString(item.Object, "spec", "clusterName"); name != "" {
+		return name
+	}
+	if name := item.GetLabels()[ClusterNameLabel]; name != "" {
+		return name
+	}
+	for _, owner := range item.GetOwnerReferences() {
+		if owner.Kind == "Cluster" {
+			return owner.Name
+		}
+	}
+	return ""
+}
+
+// summaryCondition returns the summary condition of an object, Available of
+// v1beta2 or Ready of v1beta1
+func summaryCondition(item unstructured.Unstructured) (metav1.Condition, bool) {
+	conditions := Conditions(item)
+	for _, conditionType := range readyConditions {
+		if condition, ok := conditions[conditionType]; ok {
+			return condition, true
+		}
+	}
+	return metav1.Condition{}, false
+}
+
+// newFinding returns the finding of an object with the details of its
+// summary condition. The finding is healthy if the condition is True or not
+// reported.
+func newFinding(item unstructured.Unstructured) checker.Finding {
+	finding := checker.Finding{
+		Namespace: item.GetNamespace(),
+		Name:      item.GetName(),
+		Healthy:   true,
+		Labels:    map[string]string{},
+		CreatedAt: item.GetCreationTimestamp().Time,
+	}
+	if name := clusterName(item); name != "" {
+		finding.Labels["cluster"] = name
+	}
+
+	if condition, ok := summaryCondition(item); ok {
+		finding.LastTransition = condition.LastTransitionTime.Time
+		if condition.Status != metav1.ConditionTrue {
+			finding.Healthy = false
+			finding.Reason = condition.Reason
+			finding.Message = fmt.Sprintf("%s=%s", condition.Type, condition.Status)
+			if condition.Message != "" {
+				finding.Message += ": " + condition.Message
+			}
+		}
+	}
+	return finding
+}
+
+// replicasFinding returns the finding of a KubeadmControlPlane,
+// MachineDeployment or MachineSet. It is healthy if the summary condition is
+// True, all desired replicas are ready and the phase, if reported, is Running.
+// While scaling, e.g. ScalingUp, or with replicas not ready yet, it is healthy
+// until it is stuck for the stuck timeout since the last transition, see
+// transitionTime. A summary condition which is not True without scaling and
+// the phases Failed and Unknown are unhealthy immediately.
+func replicasFinding(item unstructured.Unstructured, stuckTimeout time.Duration, now time.Time) checker.Finding {
+	finding := newFinding(item)
+
+	desired, found, _ := unstructured.NestedInt64(item.Object, "spec", "replicas")
+	if !found {
+		desired = 1
+	}
+	ready, _, _ := unstructured.NestedInt64(item.Object, "status", "readyReplicas")
+	finding.Status = fmt.Sprintf("%d/%d ready", ready, desired)
+
+	phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+	if phase != "" {
+		finding.Status = phase + ", " + finding.Status
+	}
+	switch phase {
+	case "Failed", "Unknown":
+		finding.Healthy = false
+		finding.Reason = phase
+		return finding
+	}
+
+	scaling := phase != "" && phase != "Running"
+	if !scaling && ready >= desired {
+		return finding
+	}
+
+	// the summary condition is not final while scaling
+	finding.Healthy = false
+	if finding.Reason == "" {
+		finding.Reason = "ReplicasNotReady"
+		if scaling {
+			finding.Reason = phase
+		}
+	}
+	stuck(&finding, transitionTime(item), stuckTimeout, now)
+	return finding
+}
+
+// transitionTime returns the time an object entered its current state: the
+// last transition of the summary condition, of any condition if there is no
+// summary condition, or the creation time without conditions
+func transitionTime(item unstructured.Unstructured) time.Time {
+	if condition, ok := summaryCondition(item); ok && !condition.LastTransitionTime.IsZero() {
+		return condition.LastTransitionTime.Time
+	}
+	since := time.Time{}
+	for _, condition := range Conditions(item) {
+		if condition.LastTransitionTime.After(since) {
+			since = condition.LastTransitionTime.Time
+		}
+	}
+	if since.IsZero() {
+		since = item.GetCreationTimestamp().Time
+	}
+	return since
+}
+
+// stuck marks a finding in transition since the given time as stuck after the
+// stuck timeout, before it is healthy as the conditions are not final
+func stuck(finding *checker.Finding, since time.Time, stuckTimeout time.Duration, now time.Time) {
+	if age := now.Sub(since); age > stuckTimeout {
+		finding.Healthy = false
+		finding.Reason = "Stuck"
+		finding.Message = fmt.Sprintf("%s for %s", finding.Status, age.Truncate(time.Second))
+		return
+	}
+	finding.Healthy = true
+	finding.Reason = ""
+	finding.Message = ""
+}
+
+// machineFinding returns the finding of a Machine. It is healthy if it is
+// Running and its summary condition is True. Machines in the Pending,
+// Provisioning, Provisioned (waiting for the node) or Deleting phase are
+// healthy until they are stuck for the stuck timeout.
+func machineFinding(item unstructured.Unstructured, stuckTimeout time.Duration, now time.Time) checker.Finding {
+	finding := newFinding(item)
+
+	phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+	if phase == "" {
+		phase = "Unknown"
+	}
+	finding.Status = phase
+
+	switch phase {
+	case "Running":
+	case "Pending", "Provisioning", "Provisioned", "Deleting":
+		since := item.GetCreationTimestamp().Time
+		if phase == "Deleting" && item.GetDeletionTimestamp() != nil {
+			since = item.GetDeletionTimestamp().Time
+		}
+		stuck(&finding, since, stuckTimeout, now)
+	default:
+		finding.Healthy = false
+		if finding.Reason == "" {
+			finding.Reason = phase
+		}
+	}
+	return finding
+}
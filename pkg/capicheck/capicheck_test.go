@@ -0,0 +1,198 @@
+package capicheck
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// testObjects returns a v1beta1 list of the kind with the objects of the
+// clusters prod and dev
+func testObjects(kind string, items ...string) string {
+	return `{"apiVersion":"cluster.x-k8s.io/v1beta1","kind":"` + kind + `List","metadata":{},"items":[` + strings.Join(items, ",") + `]}`
+}
+
+func TestCheckerRun(t *testing.T) {
+	created := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	testManagement(t, map[string][]string{
+		"cluster.x-k8s.io/v1beta1":              {"clusters", "machinedeployments", "machinesets", "machines"},
+		"controlplane.cluster.x-k8s.io/v1beta1": {"kubeadmcontrolplanes"},
+	}, map[string]string{
+		"/apis/cluster.x-k8s.io/v1beta1/clusters": testClusters,
+		"/apis/controlplane.cluster.x-k8s.io/v1beta1/kubeadmcontrolplanes": testObjects("KubeadmControlPlane",
+			`{"kind":"KubeadmControlPlane","metadata":{"name":"prod-cp","namespace":"default","ownerReferences":[{"apiVersion":"cluster.x-k8s.io/v1beta1","kind":"Cluster","name":"prod","uid":"1"}]},
+			  "spec":{"replicas":3},"status":{"readyReplicas":3,"conditions":[{"type":"Ready","status":"True"}]}}`,
+			`{"kind":"KubeadmControlPlane","metadata":{"name":"dev-cp","namespace":"default","ownerReferences":[{"apiVersion":"cluster.x-k8s.io/v1beta1","kind":"Cluster","name":"dev","uid":"2"}]},
+			  "spec":{"replicas":3},"status":{"readyReplicas":1,"conditions":[{"type":"Ready","status":"False","lastTransitionTime":"`+created+`"}]}}`),
+		"/apis/cluster.x-k8s.io/v1beta1/machinedeployments": testObjects("MachineDeployment",
+			`{"kind":"MachineDeployment","metadata":{"name":"prod-md","namespace":"default"},"spec":{"clusterName":"prod","replicas":2},"status":{"phase":"Running","readyReplicas":2}}`),
+		"/apis/cluster.x-k8s.io/v1beta1/machinesets": testObjects("MachineSet"),
+		"/apis/cluster.x-k8s.io/v1beta1/machines": testObjects("Machine",
+			`{"kind":"Machine","metadata":{"name":"prod-md-1","namespace":"default","creationTimestamp":"`+created+`"},"spec":{"clusterName":"prod"},"status":{"phase":"Running"}}`,
+			`{"kind":"Machine","metadata":{"name":"prod-md-2","namespace":"default","creationTimestamp":"`+created+`"},"spec":{"clusterName":"prod"},"status":{"phase":"Provisioning"}}`,
+			`{"kind":"Machine","metadata":{"name":"dev-cp-1","namespace":"default","creationTimestamp":"`+created+`"},"spec":{"clusterName":"dev"},"status":{"phase":"Failed"}}`),
+	})
+
+	results := NewChecker(checker.Options{}).Run(context.Background())
+	expected := []struct {
+		name    string
+		passed  bool
+		message string
+	}{
+		{"CAPI Clusters", false, "1 of 2 Clusters not healthy"},
+		{"CAPI KubeadmControlPlanes", false, "1 of 2 KubeadmControlPlanes not healthy"},
+		{"CAPI MachineDeployments", true, "All 1 MachineDeployments are healthy"},
+		{"CAPI MachineSets", true, "No MachineSets found"},
+		{"CAPI Machines", false, "2 of 3 Machines not healthy"},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d results, got %+v", len(expected), results)
+	}
+	for i, e := range expected {
+		if results[i].Name != e.name || results[i].Passed != e.passed || results[i].Message != e.message || results[i].Category != checker.CategoryCAPI {
+			t.Errorf("Expected %s %v %q, got %s %v %q", e.name, e.passed, e.message, results[i].Name, results[i].Passed, results[i].Message)
+		}
+	}
+
+	stuck := results[4].Findings[1]
+	if stuck.Healthy || stuck.Reason != "Stuck" || !strings.HasPrefix(stuck.Message, "Provisioning for 1h") {
+		t.Errorf("Expected the machine stuck in Provisioning, got %+v", stuck)
+	}
+	if cp := results[1].Findings[1]; cp.Status != "1/3 ready" || cp.Labels["cluster"] != "dev" {
+		t.Errorf("Expected 1 of 3 ready replicas of the control plane of dev, got %+v", cp)
+	}
+
+	results = NewChecker(checker.Options{CAPIStuckTimeout: 2 * time.Hour}).Run(context.Background())
+	if len(results) != 5 || !results[4].Findings[1].Healthy || !results[1].Findings[1].Healthy {
+		t.Errorf("Expected the provisioning machine and the scaling control plane to be healthy within the stuck timeout, got %+v", results)
+	}
+}
+
+func TestCheckerRunWithoutClusterAPI(t *testing.T) {
+	testManagement(t, map[string][]string{}, map[string]string{})
+
+	results := NewChecker(checker.Options{}).Run(context.Background())
+	if len(results) != 1 || results[0].Passed || results[0].Name != "CAPI Clusters" || !strings.Contains(results[0].Message, "Cluster API is not available") {
+		t.Errorf("Expected a failed result without Cluster API, got %+v", results)
+	}
+}
+
+func TestCheckClusters(t *testing.T) {
+	c := NewChecker(checker.Options{}).(*Checker)
+	items := []unstructured.Unstructured{}
+	for _, object := range []struct{ name, cluster string }{{"prod-md", "prod"}, {"dev-md", "dev"}} {
+		item := unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{"replicas": int64(0)}}}
+		item.SetName(object.name)
+		item.SetNamespace("default")
+		item.SetLabels(map[string]string{ClusterNameLabel: object.cluster})
+		items = append(items, item)
+	}
+
+	result := c.check(objectKinds[2], items, map[string]bool{"default/prod": true}, time.Now())
+	if len(result.Findings) != 1 || result.Findings[0].Name != "prod-md" || !result.Passed {
+		t.Errorf("Expected only the MachineDeployment of prod, got %+v", result)
+	}
+}
+
+func TestReplicasFinding(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		phase   string
+		desired int64
+		ready   int64
+		// available is the status of the Available condition, since its last
+		// transition, not reported if empty
+		available string
+		since     time.Duration
+		healthy   bool
+		status    string
+		reason    string
+	}{
+		{"ready", "", 3, 3, "True", time.Hour, true, "3/3 ready", ""},
+		{"default replicas", "", 0, 1, "", 0, true, "1/1 ready", ""},
+		{"running", "Running", 2, 2, "True", time.Hour, true, "Running, 2/2 ready", ""},
+		{"replicas not ready", "", 3, 2, "False", 10 * time.Minute, true, "2/3 ready", ""},
+		{"replicas stuck", "", 3, 2, "False", time.Hour, false, "2/3 ready", "Stuck"},
+		{"scaling up", "ScalingUp", 3, 2, "False", 10 * time.Minute, true, "ScalingUp, 2/3 ready", ""},
+		{"stuck in scaling up", "ScalingUp", 3, 2, "False", time.Hour, false, "ScalingUp, 2/3 ready", "Stuck"},
+		{"scaling down", "ScalingDown", 1, 1, "True", 10 * time.Minute, true, "ScalingDown, 1/1 ready", ""},
+		{"stuck in scaling down", "ScalingDown", 1, 1, "True", time.Hour, false, "ScalingDown, 1/1 ready", "Stuck"},
+		{"not available", "", 1, 1, "False", 10 * time.Minute, false, "1/1 ready", "MinimumReplicasUnavailable"},
+		{"running not available", "Running", 2, 2, "False", time.Minute, false, "Running, 2/2 ready", "MinimumReplicasUnavailable"},
+		{"failed", "Failed", 1, 1, "True", time.Minute, false, "Failed, 1/1 ready", "Failed"},
+		{"unknown", "Unknown", 1, 0, "False", time.Minute, false, "Unknown, 0/1 ready", "Unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := unstructured.Unstructured{Object: map[string]interface{}{"status": map[string]interface{}{"readyReplicas": tt.ready}}}
+			if tt.desired > 0 {
+				unstructured.SetNestedField(item.Object, tt.desired, "spec", "replicas")
+			}
+			if tt.phase != "" {
+				unstructured.SetNestedField(item.Object, tt.phase, "status", "phase")
+			}
+			if tt.available != "" {
+				unstructured.SetNestedSlice(item.Object, []interface{}{
+					map[string]interface{}{"type": "Available", "status": tt.available, "reason": "MinimumReplicasUnavailable", "lastTransitionTime": now.Add(-tt.since).Format(time.RFC3339)},
+				}, "status", "conditions")
+			}
+			// the object is older than the stuck timeout, only the transition counts
+			item.SetCreationTimestamp(metav1.NewTime(now.Add(-24 * time.Hour)))
+
+			finding := replicasFinding(item, DefaultStuckTimeout, now)
+			if finding.Healthy != tt.healthy || finding.Status != tt.status || finding.Reason != tt.reason {
+				t.Errorf("replicasFinding() = %v, %q, %q, want %v, %q, %q", finding.Healthy, finding.Status, finding.Reason, tt.healthy, tt.status, tt.reason)
+			}
+		})
+	}
+}
+
+func TestMachineFinding(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		phase   string
+		age     time.Duration
+		deleted time.Duration
+		ready   string
+		healthy bool
+		reason  string
+	}{
+		{"running", "Running", time.Hour, 0, "True", true, ""},
+		{"running not ready", "Running", time.Hour, 0, "False", false, "NodeNotReady"},
+		{"provisioning", "Provisioning", 10 * time.Minute, 0, "False", true, ""},
+		{"stuck in provisioning", "Provisioning", time.Hour, 0, "False", false, "Stuck"},
+		{"deleting", "Deleting", 2 * time.Hour, 10 * time.Minute, "", true, ""},
+		{"stuck in deleting", "Deleting", 2 * time.Hour, time.Hour, "", false, "Stuck"},
+		{"failed", "Failed", time.Hour, 0, "", false, "Failed"},
+		{"unknown", "", time.Hour, 0, "", false, "Unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := unstructured.Unstructured{Object: map[string]interface{}{"status": map[string]interface{}{"phase": tt.phase}}}
+			if tt.ready != "" {
+				unstructured.SetNestedSlice(item.Object, []interface{}{
+					map[string]interface{}{"type": "Ready", "status": tt.ready, "reason": "NodeNotReady"},
+				}, "status", "conditions")
+			}
+			item.SetCreationTimestamp(metav1.NewTime(now.Add(-tt.age)))
+			if tt.deleted > 0 {
+				deleted := metav1.NewTime(now.Add(-tt.deleted))
+				item.SetDeletionTimestamp(&deleted)
+			}
+
+			finding := machineFinding(item, DefaultStuckTimeout, now)
+			if finding.Healthy != tt.healthy || finding.Reason != tt.reason {
+				t.Errorf("machineFinding() = %v, %q, want %v, %q", finding.Healthy, finding.Reason, tt.healthy, tt.reason)
+			}
+		})
+	}
+}
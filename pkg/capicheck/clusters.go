@@ -0,0 +1,287 @@
+// Package capicheck reads the Cluster API objects of a management cluster,
+// e.g. to discover the workload clusters and their kubeconfigs.
+package capicheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"github.com/eumel8/clustercheck/pkg/common"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Group is the API group of the Cluster API core objects
+const Group = "cluster.x-k8s.io"
+
+// Versions are the Cluster API versions in order of preference, the first
+// version served by the management cluster is used
+var Versions = []string{"v1beta2", "v1beta1"}
+
+// PhaseProvisioned is the phase of a fully provisioned cluster
+const PhaseProvisioned = "Provisioned"
+
+// readyConditions are the summary conditions of the Cluster API versions:
+// Available of v1beta2 and Ready of v1beta1
+var readyConditions = []string{"Available", "Ready"}
+
+// WorkloadCluster is a Cluster object of the management cluster
+type WorkloadCluster struct {
+	Namespace string
+	Name      string
+	Phase     string
+	// Condition is the summary condition, Status its status and Reason and
+	// Message the details if not True. Condition is empty if not reported.
+	Condition      string
+	Status         string
+	Reason         string
+	Message        string
+	CreatedAt      time.Time
+	LastTransition time.Time
+}
+
+// ObjectName returns the namespaced name of the cluster
+func (c WorkloadCluster) ObjectName() string {
+	return c.Namespace + "/" + c.Name
+}
+
+// Healthy reports whether the cluster is provisioned and its summary
+// condition, if reported, is True
+func (c WorkloadCluster) Healthy() bool {
+	return c.Phase == PhaseProvisioned && (c.Condition == "" || c.Status == string(metav1.ConditionTrue))
+}
+
+// Result returns the provisioning state of the cluster as check result
+func (c WorkloadCluster) Result() checker.Result {
+	phase := c.Phase
+	if phase == "" {
+		phase = "Unknown"
+	}
+	result := checker.Result{
+		Name:     "Cluster API Provisioning",
+		Category: checker.CategoryCAPI,
+		Target:   c.ObjectName(),
+		Passed:   c.Healthy(),
+		Findings: []checker.Finding{{
+			Kind:           "Cluster",
+			Namespace:      c.Namespace,
+			Name:           c.Name,
+			Healthy:        c.Healthy(),
+			Status:         phase,
+			Reason:         c.Reason,
+			Message:        c.Message,
+			CreatedAt:      c.CreatedAt,
+			LastTransition: c.LastTransition,
+		}},
+	}
+
+	result.Message = fmt.Sprintf("Cluster %s is %s", c.ObjectName(), phase)
+	if c.Condition != "" {
+		result.Message += fmt.Sprintf(", %s=%s", c.Condition, c.Status)
+	}
+	if c.Reason != "" || c.Message != "" {
+		result.Message += fmt.Sprintf(" (%s)", strings.TrimPrefix(c.Reason+": "+c.Message, ": "))
+	}
+	return result
+}
+
+// Management reads the Cluster API objects and the workload cluster
+// kubeconfigs of a management cluster
+type Management struct {
+	dynamic   dynamic.Interface
+	clientset kubernetes.Interface
+	retry     checker.Retry
+	debug     bool
+}
+
+// NewManagement creates the clients of the management cluster selected by kube
+func NewManagement(kube checker.KubeOptions, retry checker.Retry, debug bool) (*Management, error) {
+	if debug {
+		fmt.Fprintf(os.Stderr, "\n[DEBUG] Cluster API Management Cluster:\n")
+		fmt.Fprintf(os.Stderr, "  Kubeconfig: %s\n", strings.Join(common.KubeConfigFiles(kube), string(filepath.ListSeparator)))
+	}
+
+	config, err := common.RESTConfig(kube)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build config: %v", err)
+	}
+
+	if debug {
+		fmt.Fprintf(os.Stderr, "  API Server: %s\n", config.Host)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clientset: %v", err)
+	}
+	return &Management{dynamic: dynamicClient, clientset: clientset, retry: retry, debug: debug}, nil
+}
+
+// List lists the Cluster API objects of the resource, e.g. "clusters", in
+// the namespace (all namespaces if empty) with the first served version of
+// the group. A NotServedError is returned if no version is served.
+func (m *Management) List(ctx context.Context, group string, resource string, namespace string, selector string) ([]unstructured.Unstructured, error) {
+	gvr, err := m.served(group, resource)
+	if err != nil {
+		return nil, err
+	}
+	if m.debug {
+		fmt.Fprintf(os.Stderr, "  Operation: List %s (namespace: %s, selector: %s)\n", gvr.String(), namespace, selector)
+	}
+
+	var list *unstructured.UnstructuredList
+	_, err = m.retry.Do(ctx, common.IsRetryableAPIError, func() error {
+		var err error
+		list, err = m.dynamic.Resource(gvr).Namespace(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s.%s: %v", resource, group, err)
+	}
+
+	if m.debug {
+		fmt.Fprintf(os.Stderr, "  Total %s: %d\n", resource, len(list.Items))
+	}
+	return list.Items, nil
+}
+
+// served returns the resource of the first served version of the group. The
+// API discovery is used, as it is readable without permissions on the
+// resource, unlike the resource itself which is forbidden then.
+func (m *Management) served(group string, resource string) (schema.GroupVersionResource, error) {
+	for _, version := range Versions {
+		gvr := schema.GroupVersionResource{Group: group, Version: version, Resource: resource}
+		resources, err := m.clientset.Discovery().ServerResourcesForGroupVersion(gvr.GroupVersion().String())
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return gvr, fmt.Errorf("failed to discover %s: %v", gvr.GroupVersion().String(), err)
+		}
+		for _, apiResource := range resources.APIResources {
+			if apiResource.Name == resource {
+				return gvr, nil
+			}
+		}
+	}
+	return schema.GroupVersionResource{}, &NotServedError{Resource: resource + "." + group}
+}
+
+// NotServedError is returned if no Cluster API version of a resource is
+// served, e.g. the provider is not installed
+type NotServedError struct {
+	Resource string
+}
+
+func (e *NotServedError) Error() string {
+	return fmt.Sprintf("%s is not served by the API server", e.Resource)
+}
+
+// IsNotServed reports whether err is a NotServedError
+func IsNotServed(err error) bool {
+	var notServed *NotServedError
+	return errors.As(err, &notServed)
+}
+
+// Clusters lists the Cluster objects in the namespace (all namespaces if
+// empty) matching the label selector
+func (m *Management) Clusters(ctx context.Context, namespace string, selector string) ([]WorkloadCluster, error) {
+	items, err := m.List(ctx, Group, "clusters", namespace, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := []WorkloadCluster{}
+	for _, item := range items {
+		clusters = append(clusters, workloadCluster(item))
+	}
+	return clusters, nil
+}
+
+// workloadCluster reads the phase and summary condition of a Cluster object
+func workloadCluster(item unstructured.Unstructured) WorkloadCluster {
+	cluster := WorkloadCluster{
+		Namespace: item.GetNamespace(),
+		Name:      item.GetName(),
+		CreatedAt: item.GetCreationTimestamp().Time,
+	}
+	cluster.Phase, _, _ = unstructured.NestedString(item.Object, "status", "phase")
+
+	if condition, ok := summaryCondition(item); ok {
+		cluster.Condition = condition.Type
+		cluster.Status = string(condition.Status)
+		cluster.LastTransition = condition.LastTransitionTime.Time
+		if condition.Status != metav1.ConditionTrue {
+			cluster.Reason = condition.Reason
+			cluster.Message = condition.Message
+		}
+	}
+	return cluster
+}
+
+// Conditions returns the status conditions of a Cluster API object by type
+func Conditions(item unstructured.Unstructured) map[string]metav1.Condition {
+	conditions := map[string]metav1.Condition{}
+	list, _, _ := unstructured.NestedSlice(item.Object, "status", "conditions")
+	for _, entry := range list {
+		fields, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		condition := metav1.Condition{}
+		condition.Type, _, _ = unstructured.NestedString(fields, "type")
+		status, _, _ := unstructured.NestedString(fields, "status")
+		condition.Status = metav1.ConditionStatus(status)
+		condition.Reason, _, _ = unstructured.NestedString(fields, "reason")
+		condition.Message, _, _ = unstructured.NestedString(fields, "message")
+		if transition, _, _ := unstructured.NestedString(fields, "lastTransitionTime"); transition != "" {
+			if t, err := time.Parse(time.RFC3339, transition); err == nil {
+				condition.LastTransitionTime = metav1.NewTime(t)
+			}
+		}
+		if condition.Type != "" {
+			conditions[condition.Type] = condition
+		}
+	}
+	return conditions
+}
+
+// Kubeconfig returns the admin kubeconfig of the workload cluster, stored by
+// Cluster API in the key value of the Secret <cluster>-kubeconfig
+func (m *Management) Kubeconfig(ctx context.Context, cluster WorkloadCluster) ([]byte, error) {
+	name := cluster.Name + "-kubeconfig"
+	if m.debug {
+		fmt.Fprintf(os.Stderr, "  Operation: Get Secret %s/%s\n", cluster.Namespace, name)
+	}
+
+	var data []byte
+	_, err := m.retry.Do(ctx, common.IsRetryableAPIError, func() error {
+		secret, err := m.clientset.CoreV1().Secrets(cluster.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		data = secret.Data["value"]
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig Secret %s/%s: %v", cluster.Namespace, name, err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("kubeconfig Secret %s/%s has no key value", cluster.Namespace, name)
+	}
+	return data, nil
+}
@@ -0,0 +1,151 @@
+package capicheck
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// testClusters is a v1beta1 ClusterList with a provisioned and a provisioning cluster
+const testClusters = `{"apiVersion":"cluster.x-k8s.io/v1beta1","kind":"ClusterList","metadata":{},"items":[
+{"apiVersion":"cluster.x-k8s.io/v1beta1","kind":"Cluster","metadata":{"name":"prod","namespace":"default"},
+ "status":{"phase":"Provisioned","conditions":[{"type":"Ready","status":"True","lastTransitionTime":"2026-01-02T03:04:05Z"}]}},
+{"apiVersion":"cluster.x-k8s.io/v1beta1","kind":"Cluster","metadata":{"name":"dev","namespace":"default"},
+ "status":{"phase":"Provisioning","conditions":[{"type":"Ready","status":"False","reason":"WaitingForControlPlane","message":"0 of 3 machines ready"}]}}]}`
+
+// testManagement starts a management API server with the API discovery of
+// the served group versions and resources and the responses by path and sets
+// KUBECONFIG
+func testManagement(t *testing.T, served map[string][]string, responses map[string]string) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if response, ok := responses[r.URL.Path]; ok {
+			w.Write([]byte(response))
+			return
+		}
+		if resources, ok := served[strings.TrimPrefix(r.URL.Path, "/apis/")]; ok {
+			list := metav1.APIResourceList{GroupVersion: strings.TrimPrefix(r.URL.Path, "/apis/")}
+			for _, resource := range resources {
+				list.APIResources = append(list.APIResources, metav1.APIResource{Name: resource, Namespaced: true, Verbs: []string{"list"}})
+			}
+			json.NewEncoder(w).Encode(list)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404}`))
+	}))
+	t.Cleanup(server.Close)
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["management"] = &clientcmdapi.Cluster{Server: server.URL}
+	config.AuthInfos["management"] = &clientcmdapi.AuthInfo{}
+	config.Contexts["management"] = &clientcmdapi.Context{Cluster: "management", AuthInfo: "management"}
+	config.CurrentContext = "management"
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+	t.Setenv("KUBECONFIG", path)
+}
+
+func TestManagementClusters(t *testing.T) {
+	testManagement(t, map[string][]string{"cluster.x-k8s.io/v1beta1": {"clusters"}}, map[string]string{
+		"/apis/cluster.x-k8s.io/v1beta1/clusters": testClusters,
+		"/api/v1/namespaces/default/secrets/prod-kubeconfig": `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"prod-kubeconfig","namespace":"default"},"data":{"value":"` +
+			base64.StdEncoding.EncodeToString([]byte("apiVersion: v1\nkind: Config\n")) + `"}}`,
+	})
+	management, err := NewManagement(checker.KubeOptions{}, checker.Retry{}, false)
+	if err != nil {
+		t.Fatalf("NewManagement() returned error: %v", err)
+	}
+
+	clusters, err := management.Clusters(context.Background(), "", "")
+	if err != nil {
+		t.Fatalf("Clusters() returned error: %v", err)
+	}
+	if len(clusters) != 2 {
+		t.Fatalf("Expected 2 clusters of v1beta1, got %+v", clusters)
+	}
+
+	prod, dev := clusters[0], clusters[1]
+	if prod.ObjectName() != "default/prod" || !prod.Healthy() || prod.LastTransition.IsZero() {
+		t.Errorf("Expected the healthy cluster default/prod, got %+v", prod)
+	}
+	if dev.Healthy() || dev.Phase != "Provisioning" || dev.Reason != "WaitingForControlPlane" {
+		t.Errorf("Expected the provisioning cluster dev, got %+v", dev)
+	}
+
+	kubeconfig, err := management.Kubeconfig(context.Background(), prod)
+	if err != nil || !strings.Contains(string(kubeconfig), "kind: Config") {
+		t.Errorf("Expected the kubeconfig of prod, got %q, %v", kubeconfig, err)
+	}
+	if _, err := management.Kubeconfig(context.Background(), dev); err == nil || !strings.Contains(err.Error(), "default/dev-kubeconfig") {
+		t.Errorf("Expected error for the missing Secret of dev, got %v", err)
+	}
+
+	if _, err := management.List(context.Background(), Group, "machines", "", ""); !IsNotServed(err) || !strings.Contains(err.Error(), "machines.cluster.x-k8s.io is not served") {
+		t.Errorf("Expected error for a resource which is not served, got %v", err)
+	}
+	if _, err := management.Clusters(context.Background(), "other", ""); err == nil || IsNotServed(err) {
+		t.Errorf("Expected error for a failed list, got %v", err)
+	}
+}
+
+func TestWorkloadClusterResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  map[string]interface{}
+		passed  bool
+		message string
+	}{
+		{
+			"v1beta2 available",
+			map[string]interface{}{"phase": "Provisioned", "conditions": []interface{}{
+				map[string]interface{}{"type": "Available", "status": "True"},
+				map[string]interface{}{"type": "Ready", "status": "False"},
+			}},
+			true, "Cluster default/prod is Provisioned, Available=True",
+		},
+		{
+			"not ready",
+			map[string]interface{}{"phase": "Provisioned", "conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "False", "reason": "MachinesNotReady", "message": "1 of 3 not ready"},
+			}},
+			false, "Cluster default/prod is Provisioned, Ready=False (MachinesNotReady: 1 of 3 not ready)",
+		},
+		{"without conditions", map[string]interface{}{"phase": "Provisioned"}, true, "Cluster default/prod is Provisioned"},
+		{"deleting", map[string]interface{}{"phase": "Deleting"}, false, "Cluster default/prod is Deleting"},
+		{"without status", nil, false, "Cluster default/prod is Unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := unstructured.Unstructured{Object: map[string]interface{}{}}
+			item.SetNamespace("default")
+			item.SetName("prod")
+			if tt.status != nil {
+				item.Object["status"] = tt.status
+			}
+
+			result := workloadCluster(item).Result()
+			if result.Passed != tt.passed || result.Message != tt.message {
+				t.Errorf("Result() = %v, %q, want %v, %q", result.Passed, result.Message, tt.passed, tt.message)
+			}
+			if result.Category != checker.CategoryCAPI || len(result.Findings) != 1 || result.Findings[0].Kind != "Cluster" {
+				t.Errorf("Expected a Cluster finding in the capi category, got %+v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Categories of the built-in checks
+const (
+	CategoryPods       = "pods"
+	CategoryFlux       = "flux"
+	CategoryPrometheus = "prometheus"
+	CategoryCAPI       = "capi"
+)
+
+// Severities of check results
+const (
+	SeverityCritical = "critical"
+	SeverityWarning  = "warning"
+)
+
+// DefaultParallelism is the default number of concurrent queries of a check
+const DefaultParallelism = 4
+
+// DefaultCheckTimeout is the default timeout of a single check or query
+const DefaultCheckTimeout = 30 * time.Second
+
+// Result represents the result of a health check
+type Result struct {
+	Name     string
+	Category string
+	Passed   bool
+	Message  string
+	// Severity is SeverityCritical (or empty) for checks counting towards the
+	// health score, failed SeverityWarning checks are only reported
+	Severity string
+	// Target is the scope the check ran against, e.g. the cluster label of a Prometheus query
+	Target string
+	// TargetSource describes how the target was resolved, e.g. the source of the cluster label
+	TargetSource string
+	// Query and Value hold the query behind the check and the observed value, if any
+	Query string
+	Value string
+	// Endpoint is the Prometheus endpoint which answered the query
+	Endpoint string
+	// TimedOut is set if the check was aborted by the run or check timeout
+	TimedOut bool
+	// Attempts is the number of attempts of the check including retries of transient errors
+	Attempts int
+	// Findings lists the state of every object inspected by the check
+	Findings  []Finding
+	StartedAt time.Time
+	Duration  time.Duration
+}
+
+// Finding describes the state of a single object inspected by a check
+type Finding struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Healthy   bool
+	Status    string
+	Reason    string
+	Message   string
+	Revision  string
+	// Labels is the label set of a Prometheus series
+	Labels map[string]string
+	// CreatedAt is the creation time of the object, LastTransition the time of its last status change
+	CreatedAt      time.Time
+	LastTransition time.Time
+}
+
+// ObjectName returns the namespaced name of the object
+func (f Finding) ObjectName() string {
+	if f.Namespace == "" {
+		return f.Name
+	}
+	return f.Namespace + "/" + f.Name
+}
+
+// Warning reports whether the result is a failed check of warning severity
+func (r Result) Warning() bool {
+	return !r.Passed && r.Severity == SeverityWarning
+}
+
+// Failed returns the findings of unhealthy objects
+func (r Result) Failed() []Finding {
+	failed := []Finding{}
+	for _, finding := range r.Findings {
+		if !finding.Healthy {
+			failed = append(failed, finding)
+		}
+	}
+	return failed
+}
+
+// Options holds the settings shared by all checkers of a run
+type Options struct {
+	Namespace string
+	Bitwarden bool
+	FQDN      string
+	Debug     bool
+	// ConfigFile is the path of the Prometheus checks configuration
+	ConfigFile string
+	// Parallelism limits the number of concurrent queries, DefaultParallelism if zero
+	Parallelism int
+	// CheckTimeout limits the duration of a single check or query, DefaultCheckTimeout if zero
+	CheckTimeout time.Duration
+	// Retries is the number of retries of transient errors, RetryBackoff the
+	// initial delay between them (DefaultRetryBackoff if zero)
+	Retries      int
+	RetryBackoff time.Duration
+	// PrometheusTLS configures the TLS connection to Prometheus
+	PrometheusTLS TLSOptions
+	// ServiceProxy queries Prometheus through the service proxy of the API
+	// server. The service is PrometheusService ("namespace/name[:port]") or
+	// discovered by the label selector PrometheusSelector.
+	ServiceProxy       bool
+	PrometheusService  string
+	PrometheusSelector string
+	// PortForward queries Prometheus through a temporary port-forward to a
+	// pod of the service, located like for ServiceProxy
+	PortForward bool
+	// PrometheusStrategy is the strategy of several Prometheus endpoints,
+	// failover (default) or compare
+	PrometheusStrategy string
+	// ClusterLabel and ShortClusterLabel override the cluster labels of the
+	// Prometheus queries
+	ClusterLabel      string
+	ShortClusterLabel string
+	// ClusterMap is a file mapping kube contexts and API server URLs to
+	// cluster labels
+	ClusterMap string
+	// ClusterLookup looks up the cluster label in Prometheus by the API
+	// server address of the kube context
+	ClusterLookup bool
+	// Kube selects the kubeconfig, context and identity of the Kubernetes API requests
+	Kube KubeOptions
+	// CAPINamespace and CAPISelector select the Cluster API clusters of the
+	// management cluster, all clusters if empty
+	CAPINamespace string
+	CAPISelector  string
+	// CAPIStuckTimeout is the duration after which a Cluster API object in
+	// transition, e.g. a Provisioning machine or a ScalingUp MachineDeployment,
+	// is reported as stuck
+	CAPIStuckTimeout time.Duration
+}
+
+// KubeOptions selects the kubeconfig, context and identity like the kubectl
+// flags of the same names. Empty fields keep the defaults of the kubeconfig.
+type KubeOptions struct {
+	// Kubeconfig is the path of the kubeconfig file, by default the files of
+	// KUBECONFIG are merged or ~/.kube/config is used
+	Kubeconfig string
+	// Context, Cluster and User override the current context and its cluster and user
+	Context string
+	Cluster string
+	User    string
+	// As impersonates a user
+	As string
+	// ClusterName replaces the kube context as name of the cluster, e.g. in
+	// the in-cluster mode. ClusterNameConfigMap ("namespace/name[:key]")
+	// reads the name from a ConfigMap instead.
+	ClusterName          string
+	ClusterNameConfigMap string
+}
+
+// TLSOptions configures the verification and client certificate of a TLS connection
+type TLSOptions struct {
+	// CAFile is a PEM bundle of CAs trusted in addition to the system roots
+	CAFile string
+	// CertFile and KeyFile are the PEM client certificate and key for mutual TLS
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name used to verify the server certificate
+	ServerName string
+	// InsecureSkipVerify disables the verification of the server certificate
+	InsecureSkipVerify bool
+}
+
+// Retry returns the retry configuration of the checks
+func (o Options) Retry() Retry {
+	return Retry{Retries: o.Retries, Backoff: o.RetryBackoff, MaxBackoff: DefaultMaxRetryBackoff}
+}
+
+// Timeout returns the timeout of a single check or query
+func (o Options) Timeout() time.Duration {
+	if o.CheckTimeout <= 0 {
+		return DefaultCheckTimeout
+	}
+	return o.CheckTimeout
+}
+
+// IsTimeout reports whether err was caused by the deadline of ctx or a network timeout
+func IsTimeout(ctx context.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+	if ctx.Err() == context.DeadlineExceeded || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Checker is implemented by every health check clustercheck can run
+type Checker interface {
+	// Name returns the display name of the check, e.g. "Pod Health"
+	Name() string
+	// Category groups related checks, e.g. "pods"
+	Category() string
+	// Run executes the check and returns one or more results
+	Run(ctx context.Context) []Result
+}
+
+// Factory creates a Checker for the given options
+type Factory func(opts Options) Checker
+
+type registration struct {
+	name    string
+	order   int
+	factory Factory
+	// optIn checkers only run if selected by name
+	optIn bool
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]registration{}
+)
+
+// Register makes a checker available under the given name. Checkers are run
+// in ascending order, checkers with the same order are sorted by name.
+// Register panics if the name is empty, the factory is nil or the name is
+// already taken, as this is a programming error.
+func Register(name string, order int, factory Factory) {
+	register(registration{name: name, order: order, factory: factory})
+}
+
+// RegisterOptIn makes a checker available under the given name like Register,
+// but it is not run by default and only if selected by name, e.g. a checker
+// which needs permissions most callers do not have.
+func RegisterOptIn(name string, order int, factory Factory) {
+	register(registration{name: name, order: order, factory: factory, optIn: true})
+}
+
+func register(reg registration) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if reg.name == "" {
+		panic("checker: Register called with empty name")
+	}
+	if reg.factory == nil {
+		panic("checker: Register factory is nil for " + reg.name)
+	}
+	if _, dup := registry[reg.name]; dup {
+		panic("checker: Register called twice for " + reg.name)
+	}
+	registry[reg.name] = reg
+}
+
+// Names returns the names of the checkers run by default in run order
+func Names() []string {
+	return names(false)
+}
+
+// OptInNames returns the names of the opt-in checkers in run order
+func OptInNames() []string {
+	return names(true)
+}
+
+// names returns the names of the default or opt-in checkers in run order
+func names(optIn bool) []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	regs := make([]registration, 0, len(registry))
+	for _, reg := range registry {
+		if reg.optIn == optIn {
+			regs = append(regs, reg)
+		}
+	}
+	sort.Slice(regs, func(i, j int) bool {
+		if regs[i].order != regs[j].order {
+			return regs[i].order < regs[j].order
+		}
+		return regs[i].name < regs[j].name
+	})
+
+	names := make([]string, 0, len(regs))
+	for _, reg := range regs {
+		names = append(names, reg.name)
+	}
+	return names
+}
+
+// Select creates the checkers registered under the given names in the given
+// order. Without names the checkers run by default are returned in run order,
+// opt-in checkers are only returned if named.
+func Select(opts Options, names ...string) ([]Checker, error) {
+	if len(names) == 0 {
+		names = Names()
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	checkers := make([]Checker, 0, len(names))
+	for _, name := range names {
+		reg, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown check %q", name)
+		}
+		checkers = append(checkers, reg.factory(opts))
+	}
+	return checkers, nil
+}
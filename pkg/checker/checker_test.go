@@ -0,0 +1,137 @@
+package checker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type fakeChecker struct {
+	name string
+	opts Options
+}
+
+func (f *fakeChecker) Name() string     { return f.name }
+func (f *fakeChecker) Category() string { return "test" }
+func (f *fakeChecker) Run(ctx context.Context) []Result {
+	return []Result{{Name: f.name, Category: f.Category(), Passed: true, Message: "OK"}}
+}
+
+func newFake(name string) Factory {
+	return func(opts Options) Checker {
+		return &fakeChecker{name: name, opts: opts}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	Register("zeta", 10, newFake("Zeta"))
+	Register("alpha", 20, newFake("Alpha"))
+	Register("beta", 10, newFake("Beta"))
+	RegisterOptIn("omega", 0, newFake("Omega"))
+
+	expected := []string{"beta", "zeta", "alpha"}
+	if names := Names(); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected names %v, got %v", expected, names)
+	}
+	if names := OptInNames(); !reflect.DeepEqual(names, []string{"omega"}) {
+		t.Errorf("Expected opt-in names [omega], got %v", names)
+	}
+
+	t.Run("select all", func(t *testing.T) {
+		checkers, err := Select(Options{Namespace: "default"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(checkers) != 3 {
+			t.Fatalf("Expected 3 checkers, got %d", len(checkers))
+		}
+		if checkers[0].Name() != "Beta" {
+			t.Errorf("Expected first checker 'Beta', got '%s'", checkers[0].Name())
+		}
+		if checkers[0].(*fakeChecker).opts.Namespace != "default" {
+			t.Error("Expected options to be passed to the factory")
+		}
+	})
+
+	t.Run("select by name", func(t *testing.T) {
+		checkers, err := Select(Options{}, "alpha", "zeta")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(checkers) != 2 || checkers[0].Name() != "Alpha" || checkers[1].Name() != "Zeta" {
+			t.Errorf("Expected checkers Alpha, Zeta in given order")
+		}
+	})
+
+	t.Run("select opt-in", func(t *testing.T) {
+		checkers, err := Select(Options{}, "omega", "beta")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(checkers) != 2 || checkers[0].Name() != "Omega" {
+			t.Errorf("Expected the opt-in checker Omega if selected by name")
+		}
+	})
+
+	t.Run("select unknown", func(t *testing.T) {
+		if _, err := Select(Options{}, "unknown"); err == nil {
+			t.Error("Expected error for unknown check, got nil")
+		}
+	})
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	Register("duplicate", 0, newFake("Duplicate"))
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for duplicate registration")
+		}
+	}()
+	Register("duplicate", 0, newFake("Duplicate"))
+}
+
+// timeoutError is a network error which timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"no error", ctx, nil, false},
+		{"expired context", ctx, errors.New("failed to list pods: rate limiter Wait"), true},
+		{"deadline exceeded", context.Background(), fmt.Errorf("query: %w", context.DeadlineExceeded), true},
+		{"network timeout", context.Background(), &url.Error{Op: "Get", URL: "https://prometheus", Err: timeoutError{}}, true},
+		{"other error", context.Background(), errors.New("connection refused"), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsTimeout(tt.ctx, tt.err); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestOptionsTimeout(t *testing.T) {
+	if timeout := (Options{}).Timeout(); timeout != DefaultCheckTimeout {
+		t.Errorf("Expected default timeout %s, got %s", DefaultCheckTimeout, timeout)
+	}
+	if timeout := (Options{CheckTimeout: time.Minute}).Timeout(); timeout != time.Minute {
+		t.Errorf("Expected timeout 1m, got %s", timeout)
+	}
+}
@@ -0,0 +1,101 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Defaults of the retries of transient errors
+const (
+	DefaultRetries         = 2
+	DefaultRetryBackoff    = 500 * time.Millisecond
+	DefaultMaxRetryBackoff = 10 * time.Second
+)
+
+// Retry configures the retries of transient errors with exponential backoff and jitter
+type Retry struct {
+	// Retries is the number of retries after the first attempt
+	Retries int
+	// Backoff is the delay before the first retry, doubled for every further retry
+	Backoff time.Duration
+	// MaxBackoff caps the delay between two attempts
+	MaxBackoff time.Duration
+}
+
+// Do calls fn until it succeeds, fails with an error which is not retryable,
+// the retries are exhausted or ctx is done. It returns the number of attempts
+// and the error of the last attempt.
+func (r Retry) Do(ctx context.Context, retryable func(error) bool, fn func() error) (int, error) {
+	attempts := 0
+	for {
+		attempts++
+		err := fn()
+		if err == nil || attempts > r.Retries || !retryable(err) || ctx.Err() != nil {
+			return attempts, err
+		}
+
+		timer := time.NewTimer(r.delay(attempts))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return attempts, err
+		case <-timer.C:
+		}
+	}
+}
+
+// delay returns the exponential backoff before the given retry, randomized
+// between half and the full backoff
+func (r Retry) delay(retry int) time.Duration {
+	backoff := r.Backoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	maxBackoff := r.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxRetryBackoff
+	}
+
+	for i := 1; i < retry && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff/2 + rand.N(backoff/2+1)
+}
+
+// IsNetworkError reports whether err is a transient network error: a
+// net.Error like a timeout or a refused or reset connection, a connection
+// closed early or a failed TLS handshake. Certificate verification errors are
+// permanent and not reported, as well as request errors like an unsupported
+// URL scheme.
+func IsNetworkError(err error) bool {
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCert x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var verification *tls.CertificateVerificationError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalidCert) ||
+		errors.As(err, &hostname) || errors.As(err, &verification) {
+		return false
+	}
+
+	// a url.Error is a net.Error itself, only its cause tells whether the
+	// request may succeed on retry
+	var urlErr *url.Error
+	for errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	var netErr net.Error
+	var alert tls.AlertError
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.As(err, &alert)
+}
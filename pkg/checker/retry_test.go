@@ -0,0 +1,105 @@
+package checker
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryDo(t *testing.T) {
+	transient := errors.New("transient")
+	permanent := errors.New("permanent")
+	retryable := func(err error) bool { return err == transient }
+
+	tests := []struct {
+		name     string
+		retries  int
+		errs     []error
+		attempts int
+		err      error
+	}{
+		{"success", 2, []error{nil}, 1, nil},
+		{"success after retry", 2, []error{transient, transient, nil}, 3, nil},
+		{"retries exhausted", 2, []error{transient, transient, transient, nil}, 3, transient},
+		{"permanent error", 2, []error{permanent, nil}, 1, permanent},
+		{"no retries", 0, []error{transient, nil}, 1, transient},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			retry := Retry{Retries: tt.retries, Backoff: time.Millisecond}
+			attempts, err := retry.Do(context.Background(), retryable, func() error {
+				calls++
+				return tt.errs[calls-1]
+			})
+			if attempts != tt.attempts || calls != tt.attempts {
+				t.Errorf("Expected %d attempts, got %d (%d calls)", tt.attempts, attempts, calls)
+			}
+			if err != tt.err {
+				t.Errorf("Expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestRetryDoCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	retry := Retry{Retries: 10, Backoff: time.Second}
+	attempts, err := retry.Do(ctx, func(error) bool { return true }, func() error {
+		return errors.New("unavailable")
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("Expected the backoff to be aborted after 1 attempt, got %d attempts (%v)", attempts, err)
+	}
+	if time.Since(started) > time.Second {
+		t.Errorf("Expected the retry to stop with the context, took %s", time.Since(started))
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	retry := Retry{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for i, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		for n := 0; n < 20; n++ {
+			delay := retry.delay(i + 1)
+			if delay < max/2 || delay > max {
+				t.Errorf("Retry %d: expected delay between %s and %s, got %s", i+1, max/2, max, delay)
+			}
+		}
+	}
+}
+
+func TestIsNetworkError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", &url.Error{Op: "Get", URL: "https://prometheus", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, true},
+		{"connection reset", &url.Error{Op: "Get", URL: "https://prometheus", Err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}}, true},
+		{"network timeout", timeoutError{}, true},
+		{"unexpected EOF", &url.Error{Op: "Get", URL: "https://prometheus", Err: io.ErrUnexpectedEOF}, true},
+		{"EOF", &url.Error{Op: "Get", URL: "https://prometheus", Err: io.EOF}, true},
+		{"TLS handshake failure", &url.Error{Op: "Get", URL: "https://prometheus", Err: tls.AlertError(40)}, true},
+		{"unknown authority", &url.Error{Op: "Get", URL: "https://prometheus", Err: x509.UnknownAuthorityError{}}, false},
+		{"unsupported scheme", &url.Error{Op: "Get", URL: "ftp://prometheus", Err: errors.New(`unsupported protocol scheme "ftp"`)}, false},
+		{"other error", errors.New("invalid character"), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsNetworkError(tt.err); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// InClusterContext is the context name of the in-cluster config
+const InClusterContext = "in-cluster"
+
+// restInClusterConfig loads the config of the pod service account, replaced by tests
+var restInClusterConfig = rest.InClusterConfig
+
+// DefaultClusterNameKey is the key of the cluster name in the ConfigMap
+const DefaultClusterNameKey = "cluster-name"
+
+// KubeContext is the kube context selected by the kubeconfig loading rules
+type KubeContext struct {
+	Name        string
+	ClusterName string
+	// Cluster is nil if the kubeconfig does not define the cluster
+	Cluster *clientcmdapi.Cluster
+}
+
+// ClientConfig returns the kubeconfig of the client-go loading rules like
+// kubectl: the file of opts.Kubeconfig, the merged files of KUBECONFIG or
+// ~/.kube/config, with the context, cluster, user and impersonation of opts
+func ClientConfig(opts checker.KubeOptions) clientcmd.ClientConfig {
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: opts.Context,
+		Context: clientcmdapi.Context{
+			Cluster:  opts.Cluster,
+			AuthInfo: opts.User,
+		},
+		AuthInfo: clientcmdapi.AuthInfo{
+			Impersonate: opts.As,
+		},
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules(opts), overrides)
+}
+
+// loadingRules returns the client-go loading rules of opts. Without KUBECONFIG
+// the kubeconfig of the current HOME is read, see GetKubeConfig.
+func loadingRules(opts checker.KubeOptions) *clientcmd.ClientConfigLoadingRules {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = opts.Kubeconfig
+	if os.Getenv(clientcmd.RecommendedConfigPathEnvVar) == "" {
+		rules.Precedence = []string{GetKubeConfig()}
+	}
+	return rules
+}
+
+// RESTConfig returns the REST client config of the kubeconfig selected by
+// opts, or the in-cluster config of the pod service account without kubeconfig
+func RESTConfig(opts checker.KubeOptions) (*rest.Config, error) {
+	if InCluster(opts) {
+		return inClusterConfig(opts)
+	}
+	return ClientConfig(opts).ClientConfig()
+}
+
+// InCluster reports whether clustercheck runs in a pod without kubeconfig
+// file and falls back to the service account of the pod
+func InCluster(opts checker.KubeOptions) bool {
+	if opts.Kubeconfig != "" {
+		return false
+	}
+	for _, path := range loadingRules(opts).GetLoadingPrecedence() {
+		if _, err := os.Stat(path); err == nil {
+			return false
+		}
+	}
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" && os.Getenv("KUBERNETES_SERVICE_PORT") != ""
+}
+
+// inClusterConfig returns the config of the pod service account with the
+// impersonation of opts
+func inClusterConfig(opts checker.KubeOptions) (*rest.Config, error) {
+	config, err := restInClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load in-cluster config: %v", err)
+	}
+	config.Impersonate.UserName = opts.As
+	return config, nil
+}
+
+// KubeConfigFiles returns the kubeconfig files read for opts in the order of precedence
+func KubeConfigFiles(opts checker.KubeOptions) []string {
+	if opts.Kubeconfig != "" {
+		return []string{opts.Kubeconfig}
+	}
+	return loadingRules(opts).GetLoadingPrecedence()
+}
+
+// CurrentKubeContext returns the context selected by opts and its cluster.
+// In-cluster the context is InClusterContext with the API server of the pod.
+func CurrentKubeContext(opts checker.KubeOptions) (KubeContext, error) {
+	if InCluster(opts) {
+		config, err := inClusterConfig(opts)
+		if err != nil {
+			return KubeContext{}, err
+		}
+		return KubeContext{Name: InClusterContext, ClusterName: InClusterContext, Cluster: &clientcmdapi.Cluster{Server: config.Host}}, nil
+	}
+
+	config, err := ClientConfig(opts).RawConfig()
+	if err != nil {
+		return KubeContext{}, err
+	}
+
+	current := KubeContext{Name: config.CurrentContext}
+	if opts.Context != "" {
+		current.Name = opts.Context
+	}
+	if current.Name == "" {
+		return current, fmt.Errorf("current context is not set")
+	}
+
+	if context, ok := config.Contexts[current.Name]; ok {
+		current.ClusterName = context.Cluster
+	} else if opts.Cluster == "" {
+		return current, fmt.Errorf("context %q not found", current.Name)
+	}
+	if opts.Cluster != "" {
+		current.ClusterName = opts.Cluster
+	}
+	current.Cluster = config.Clusters[current.ClusterName]
+	return current, nil
+}
+
+// CurrentContext returns the name of the context selected by opts
+func CurrentContext(opts checker.KubeOptions) (string, error) {
+	current, err := CurrentKubeContext(opts)
+	return current.Name, err
+}
+
+// MatchContexts returns the sorted contexts of the kubeconfig of opts matching
+// any of the patterns. A pattern is a context name or a glob like "prod-*", all
+// contexts are returned for "*". Every pattern must match at least one context.
+func MatchContexts(opts checker.KubeOptions, patterns []string) ([]string, error) {
+	config, err := ClientConfig(opts).RawConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	contexts := []string{}
+	matched := map[string]bool{}
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		found := false
+		for name := range config.Contexts {
+			ok, err := path.Match(pattern, name)
+			if err != nil {
+				return nil, fmt.Errorf("invalid context pattern %q: %v", pattern, err)
+			}
+			if !ok {
+				continue
+			}
+			found = true
+			if !matched[name] {
+				matched[name] = true
+				contexts = append(contexts, name)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no context matches %q", pattern)
+		}
+	}
+	sort.Strings(contexts)
+	return contexts, nil
+}
+
+// ClusterName returns the name of the cluster: the ClusterName of opts, the
+// value of the ConfigMap of opts, or the current kube context
+func ClusterName(ctx context.Context, opts checker.KubeOptions) (string, error) {
+	if opts.ClusterName != "" {
+		return opts.ClusterName, nil
+	}
+	if opts.ClusterNameConfigMap == "" {
+		return CurrentContext(opts)
+	}
+
+	namespace, name, key, err := ParseConfigMapRef(opts.ClusterNameConfigMap)
+	if err != nil {
+		return "", err
+	}
+	config, err := RESTConfig(opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to build config: %v", err)
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to create clientset: %v", err)
+	}
+
+	configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get cluster name from ConfigMap %s/%s: %v", namespace, name, err)
+	}
+	clusterName := strings.TrimSpace(configMap.Data[key])
+	if clusterName == "" {
+		return "", fmt.Errorf("ConfigMap %s/%s has no key %s", namespace, name, key)
+	}
+	return clusterName, nil
+}
+
+// ParseConfigMapRef parses a "namespace/name[:key]" ConfigMap reference. The
+// key defaults to DefaultClusterNameKey.
+func ParseConfigMapRef(ref string) (string, string, string, error) {
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok || namespace == "" || name == "" {
+		return "", "", "", fmt.Errorf("invalid ConfigMap %q, expected namespace/name[:key]", ref)
+	}
+	key := DefaultClusterNameKey
+	if n, k, ok := strings.Cut(name, ":"); ok {
+		if n == "" || k == "" {
+			return "", "", "", fmt.Errorf("invalid ConfigMap %q, expected namespace/name[:key]", ref)
+		}
+		name, key = n, k
+	}
+	return namespace, name, key, nil
+}
@@ -0,0 +1,316 @@
+package common
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// writeKubeconfig writes a kubeconfig with a context, cluster and user of the given name
+func writeKubeconfig(t *testing.T, name string, current bool) string {
+	t.Helper()
+	config := clientcmdapi.NewConfig()
+	config.Clusters[name] = &clientcmdapi.Cluster{Server: "https://" + name + ".example.com"}
+	config.AuthInfos[name] = &clientcmdapi.AuthInfo{Token: name + "-token"}
+	config.Contexts[name] = &clientcmdapi.Context{Cluster: name, AuthInfo: name}
+	if current {
+		config.CurrentContext = name
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+	return path
+}
+
+func TestCurrentKubeContext(t *testing.T) {
+	prod := writeKubeconfig(t, "prod", true)
+	dev := writeKubeconfig(t, "dev", false)
+	t.Setenv("KUBECONFIG", prod+string(filepath.ListSeparator)+dev)
+
+	tests := []struct {
+		name    string
+		opts    checker.KubeOptions
+		context string
+		cluster string
+		wantErr string
+	}{
+		{"merged KUBECONFIG", checker.KubeOptions{}, "prod", "prod", ""},
+		{"context of the second file", checker.KubeOptions{Context: "dev"}, "dev", "dev", ""},
+		{"cluster override", checker.KubeOptions{Cluster: "dev"}, "prod", "dev", ""},
+		{"explicit kubeconfig", checker.KubeOptions{Kubeconfig: dev, Context: "dev"}, "dev", "dev", ""},
+		{"explicit kubeconfig without current context", checker.KubeOptions{Kubeconfig: dev}, "", "", "current context is not set"},
+		{"unknown context", checker.KubeOptions{Context: "staging"}, "", "", `context "staging" not found`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, err := CurrentKubeContext(tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CurrentKubeContext() returned error: %v", err)
+			}
+			if current.Name != tt.context || current.ClusterName != tt.cluster || current.Cluster == nil {
+				t.Errorf("Expected context %s with cluster %s, got %+v", tt.context, tt.cluster, current)
+			}
+		})
+	}
+}
+
+func TestRESTConfig(t *testing.T) {
+	prod := writeKubeconfig(t, "prod", true)
+	dev := writeKubeconfig(t, "dev", false)
+	t.Setenv("KUBECONFIG", prod+string(filepath.ListSeparator)+dev)
+
+	config, err := RESTConfig(checker.KubeOptions{})
+	if err != nil {
+		t.Fatalf("RESTConfig() returned error: %v", err)
+	}
+	if config.Host != "https://prod.example.com" || config.BearerToken != "prod-token" {
+		t.Errorf("Expected the current context prod, got host %s and token %s", config.Host, config.BearerToken)
+	}
+
+	config, err = RESTConfig(checker.KubeOptions{Context: "prod", Cluster: "dev", User: "dev", As: "auditor"})
+	if err != nil {
+		t.Fatalf("RESTConfig() returned error: %v", err)
+	}
+	if config.Host != "https://dev.example.com" || config.BearerToken != "dev-token" || config.Impersonate.UserName != "auditor" {
+		t.Errorf("Expected cluster, user and impersonation overrides, got host %s, token %s and impersonation %+v",
+			config.Host, config.BearerToken, config.Impersonate)
+	}
+
+	if _, err := RESTConfig(checker.KubeOptions{Context: "staging"}); err == nil {
+		t.Error("Expected error for an unknown context")
+	}
+}
+
+func TestKubeConfigFiles(t *testing.T) {
+	t.Setenv("KUBECONFIG", "/a/config"+string(filepath.ListSeparator)+"/b/config")
+	if files := KubeConfigFiles(checker.KubeOptions{}); strings.Join(files, ",") != "/a/config,/b/config" {
+		t.Errorf("Expected the files of KUBECONFIG, got %v", files)
+	}
+	if files := KubeConfigFiles(checker.KubeOptions{Kubeconfig: "/c/config"}); strings.Join(files, ",") != "/c/config" {
+		t.Errorf("Expected the explicit kubeconfig, got %v", files)
+	}
+
+	t.Setenv("KUBECONFIG", "")
+	t.Setenv("HOME", "/test/home")
+	if files := KubeConfigFiles(checker.KubeOptions{}); strings.Join(files, ",") != "/test/home/.kube/config" {
+		t.Errorf("Expected the kubeconfig of HOME, got %v", files)
+	}
+}
+
+func TestInCluster(t *testing.T) {
+	tests := []struct {
+		name       string
+		kubeconfig string
+		env        string
+		host       string
+		want       bool
+	}{
+		{"pod without kubeconfig", "", "missing", "10.96.0.1", true},
+		{"explicit kubeconfig", writeKubeconfig(t, "prod", true), "missing", "10.96.0.1", false},
+		{"kubeconfig file", "", writeKubeconfig(t, "prod", true), "10.96.0.1", false},
+		{"outside of a pod", "", "missing", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env == "missing" {
+				tt.env = filepath.Join(t.TempDir(), "missing")
+			}
+			t.Setenv("KUBECONFIG", tt.env)
+			t.Setenv("KUBERNETES_SERVICE_HOST", tt.host)
+			t.Setenv("KUBERNETES_SERVICE_PORT", "443")
+
+			if got := InCluster(checker.KubeOptions{Kubeconfig: tt.kubeconfig}); got != tt.want {
+				t.Errorf("InCluster() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// inCluster simulates a pod without kubeconfig, the service account config
+// is faked as rest.InClusterConfig reads fixed paths
+func inCluster(t *testing.T, err error) {
+	t.Helper()
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.96.0.1")
+	t.Setenv("KUBERNETES_SERVICE_PORT", "443")
+
+	original := restInClusterConfig
+	restInClusterConfig = func() (*rest.Config, error) {
+		if err != nil {
+			return nil, err
+		}
+		return &rest.Config{
+			Host:            "https://10.96.0.1:443",
+			BearerTokenFile: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+		}, nil
+	}
+	t.Cleanup(func() { restInClusterConfig = original })
+}
+
+func TestInClusterConfig(t *testing.T) {
+	inCluster(t, nil)
+
+	config, err := RESTConfig(checker.KubeOptions{As: "auditor"})
+	if err != nil {
+		t.Fatalf("RESTConfig() returned error: %v", err)
+	}
+	if config.Host != "https://10.96.0.1:443" || config.BearerTokenFile != "/var/run/secrets/kubernetes.io/serviceaccount/token" || config.Impersonate.UserName != "auditor" {
+		t.Errorf("Expected the in-cluster config, got host %s, token file %s and impersonation %+v",
+			config.Host, config.BearerTokenFile, config.Impersonate)
+	}
+
+	current, err := CurrentKubeContext(checker.KubeOptions{})
+	if err != nil || current.Name != InClusterContext || current.ClusterName != InClusterContext || current.Cluster.Server != "https://10.96.0.1:443" {
+		t.Errorf("Expected the in-cluster context, got %+v, %v", current, err)
+	}
+
+	// an explicit kubeconfig takes precedence
+	current, err = CurrentKubeContext(checker.KubeOptions{Kubeconfig: writeKubeconfig(t, "prod", true)})
+	if err != nil || current.Name != "prod" {
+		t.Errorf("Expected the kubeconfig context prod, got %+v, %v", current, err)
+	}
+}
+
+func TestInClusterConfigError(t *testing.T) {
+	inCluster(t, rest.ErrNotInCluster)
+
+	if _, err := RESTConfig(checker.KubeOptions{}); err == nil || !strings.Contains(err.Error(), "failed to load in-cluster config") {
+		t.Errorf("Expected in-cluster config error, got %v", err)
+	}
+	if _, err := CurrentKubeContext(checker.KubeOptions{}); err == nil {
+		t.Error("Expected in-cluster context error, got nil")
+	}
+}
+
+func TestParseConfigMapRef(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{"monitoring/clustercheck", "monitoring/clustercheck:cluster-name", false},
+		{"monitoring/clustercheck:name", "monitoring/clustercheck:name", false},
+		{"clustercheck", "", true},
+		{"/clustercheck", "", true},
+		{"monitoring/clustercheck:", "", true},
+	}
+
+	for _, tt := range tests {
+		namespace, name, key, err := ParseConfigMapRef(tt.ref)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", tt.ref)
+			}
+			continue
+		}
+		if err != nil || namespace+"/"+name+":"+key != tt.want {
+			t.Errorf("%s: expected %s, got %s/%s:%s, %v", tt.ref, tt.want, namespace, name, key, err)
+		}
+	}
+}
+
+func TestClusterName(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/monitoring/configmaps/clustercheck" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"clustercheck","namespace":"monitoring"},"data":{"cluster-name":"prod-configmap\n"}}`))
+	}))
+	defer server.Close()
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["prod"] = &clientcmdapi.Cluster{Server: server.URL}
+	config.AuthInfos["prod"] = &clientcmdapi.AuthInfo{}
+	config.Contexts["prod"] = &clientcmdapi.Context{Cluster: "prod", AuthInfo: "prod"}
+	config.CurrentContext = "prod"
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+	t.Setenv("KUBECONFIG", path)
+
+	tests := []struct {
+		name    string
+		opts    checker.KubeOptions
+		want    string
+		wantErr string
+	}{
+		{"kube context", checker.KubeOptions{}, "prod", ""},
+		{"flag", checker.KubeOptions{ClusterName: "prod-flag", ClusterNameConfigMap: "monitoring/clustercheck"}, "prod-flag", ""},
+		{"ConfigMap", checker.KubeOptions{ClusterNameConfigMap: "monitoring/clustercheck"}, "prod-configmap", ""},
+		{"missing key", checker.KubeOptions{ClusterNameConfigMap: "monitoring/clustercheck:name"}, "", "has no key name"},
+		{"missing ConfigMap", checker.KubeOptions{ClusterNameConfigMap: "default/clustercheck"}, "", "failed to get cluster name from ConfigMap default/clustercheck"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, err := ClusterName(context.Background(), tt.opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || name != tt.want {
+				t.Errorf("ClusterName() = %s, %v, want %s", name, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchContexts(t *testing.T) {
+	t.Setenv("KUBECONFIG", strings.Join([]string{
+		writeKubeconfig(t, "prod-eu", true),
+		writeKubeconfig(t, "prod-us", false),
+		writeKubeconfig(t, "dev", false),
+	}, string(filepath.ListSeparator)))
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     string
+		wantErr  string
+	}{
+		{"all contexts", []string{"*"}, "dev,prod-eu,prod-us", ""},
+		{"glob", []string{"prod-*"}, "prod-eu,prod-us", ""},
+		{"list", []string{"prod-us", " dev", ""}, "dev,prod-us", ""},
+		{"overlapping patterns", []string{"prod-*", "prod-eu"}, "prod-eu,prod-us", ""},
+		{"no match", []string{"dev", "staging-*"}, "", `no context matches "staging-*"`},
+		{"invalid pattern", []string{"prod-["}, "", "invalid context pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contexts, err := MatchContexts(checker.KubeOptions{}, tt.patterns)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || strings.Join(contexts, ",") != tt.want {
+				t.Errorf("MatchContexts() = %v, %v, want %s", contexts, err, tt.want)
+			}
+		})
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"os"
 	"path/filepath"
 
-	"k8s.io/client-go/tools/clientcmd"
+	"github.com/eumel8/clustercheck/pkg/checker"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
-// GetKubeConfig returns the path to the kubeconfig file
+// GetKubeConfig returns the path to the kubeconfig file. Several files of
+// KUBECONFIG are returned as set, see KubeConfigFiles.
 func GetKubeConfig() string {
 	// Check if KUBECONFIG env var is set
 	if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
@@ -17,14 +19,18 @@ func GetKubeConfig() string {
 	return filepath.Join(os.Getenv("HOME"), ".kube", "config")
 }
 
-// GetCurrentContext returns the current kubernetes context
+// GetCurrentContext returns the current kubernetes context of the kubeconfig
+// loading rules, see CurrentContext
 func GetCurrentContext() (string, error) {
-	kubeconfigPath := filepath.Join(os.Getenv("HOME"), ".kube", "config")
+	return CurrentContext(checker.KubeOptions{})
+}
 
-	config, err := clientcmd.LoadFromFile(kubeconfigPath)
-	if err != nil {
-		return "", err
+// IsRetryableAPIError reports whether a Kubernetes API error is transient, e.g.
+// a throttled request, an unavailable API server or a network error
+func IsRetryableAPIError(err error) bool {
+	if apierrors.IsTooManyRequests(err) || apierrors.IsServerTimeout(err) || apierrors.IsTimeout(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsInternalError(err) {
+		return true
 	}
-
-	return config.CurrentContext, nil
+	return checker.IsNetworkError(err)
 }
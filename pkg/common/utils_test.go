@@ -1,10 +1,16 @@
 package common
 
 import (
+	"errors"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"syscall"
 	"testing"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
@@ -111,3 +117,26 @@ func TestGetKubeConfig(t *testing.T) {
 		}
 	})
 }
+
+func TestIsRetryableAPIError(t *testing.T) {
+	resource := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"throttled", apierrors.NewTooManyRequests("slow down", 1), true},
+		{"server timeout", apierrors.NewServerTimeout(resource, "list", 1), true},
+		{"unavailable", apierrors.NewServiceUnavailable("unavailable"), true},
+		{"internal error", apierrors.NewInternalError(errors.New("etcd")), true},
+		{"network error", &url.Error{Op: "Get", URL: "https://api", Err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}}, true},
+		{"forbidden", apierrors.NewForbidden(resource, "", errors.New("denied")), false},
+		{"not found", apierrors.NewNotFound(resource, "test"), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsRetryableAPIError(tt.err); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
@@ -3,43 +3,91 @@ package fluxcheck
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/eumel8/clustercheck/pkg/checker"
 	"github.com/eumel8/clustercheck/pkg/common"
 	helmv2 "github.com/fluxcd/helm-controller/api/v2"
 	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// CheckFlux checks if all Flux HelmReleases and Kustomizations are in Ready state
-func CheckFlux(namespace string, debug bool) error {
-	kubeconfigPath := common.GetKubeConfig()
+func init() {
+	checker.Register("flux", 20, NewChecker)
+}
 
-	if debug {
-		fmt.Printf("\n[DEBUG] Kubernetes API Request:\n")
-		fmt.Printf("  Kubeconfig: %s\n", kubeconfigPath)
-	}
+// Checker runs the Flux resources check as a registered checker
+type Checker struct {
+	namespace string
+	debug     bool
+	timeout   time.Duration
+	retry     checker.Retry
+	kube      checker.KubeOptions
+}
+
+// NewChecker creates a Flux resources Checker
+func NewChecker(opts checker.Options) checker.Checker {
+	return &Checker{namespace: opts.Namespace, debug: opts.Debug, timeout: opts.Timeout(), retry: opts.Retry(), kube: opts.Kube}
+}
+
+// Name returns the display name of the check
+func (c *Checker) Name() string {
+	return "Flux Resources"
+}
 
-	// Build config from kubeconfig file
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+// Category returns the check category
+func (c *Checker) Category() string {
+	return checker.CategoryFlux
+}
+
+// Run checks all HelmReleases and Kustomizations and returns a single result
+func (c *Checker) Run(ctx context.Context) []checker.Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	result, err := CheckFlux(ctx, c.kube, c.namespace, c.retry, c.debug)
 	if err != nil {
-		return fmt.Errorf("failed to build config: %v", err)
+		result.Passed = false
+		result.TimedOut = checker.IsTimeout(ctx, err)
+		result.Message = err.Error()
+		if result.Attempts > 1 {
+			result.Message = fmt.Sprintf("%v (%d attempts)", err, result.Attempts)
+		}
+	}
+	return []checker.Result{result}
+}
+
+// CheckFlux checks if all Flux HelmReleases and Kustomizations are in Ready state.
+// Transient API errors are retried. The returned error is only set if the
+// resources could not be listed.
+func CheckFlux(ctx context.Context, kube checker.KubeOptions, namespace string, retry checker.Retry, debug bool) (checker.Result, error) {
+	result := checker.Result{
+		Name:     "Flux Resources",
+		Category: checker.CategoryFlux,
+		Target:   namespace,
+		Findings: []checker.Finding{},
 	}
 
 	if debug {
-		fmt.Printf("  API Server: %s\n", config.Host)
+		fmt.Fprintf(os.Stderr, "\n[DEBUG] Kubernetes API Request:\n")
+		fmt.Fprintf(os.Stderr, "  Kubeconfig: %s\n", strings.Join(common.KubeConfigFiles(kube), string(filepath.ListSeparator)))
 	}
 
-	// Get current context for display
-	currentContext, err := common.GetCurrentContext()
+	// Build config from the kubeconfig loading rules
+	config, err := common.RESTConfig(kube)
 	if err != nil {
-		currentContext = "unknown"
+		return result, fmt.Errorf("failed to build config: %v", err)
 	}
 
-	fmt.Printf("\033[36mfluxcheck \033[0m on %s\n", currentContext)
+	if debug {
+		fmt.Fprintf(os.Stderr, "  API Server: %s\n", config.Host)
+	}
 
 	// Create a new scheme and add Flux types
 	fluxScheme := runtime.NewScheme()
@@ -50,14 +98,9 @@ func CheckFlux(namespace string, debug bool) error {
 	// Create controller-runtime client
 	k8sClient, err := client.New(config, client.Options{Scheme: fluxScheme})
 	if err != nil {
-		return fmt.Errorf("failed to create client: %v", err)
+		return result, fmt.Errorf("failed to create client: %v", err)
 	}
 
-	ctx := context.Background()
-	totalResources := 0
-	readyResources := 0
-	failedResources := []string{}
-
 	// Check HelmReleases
 	helmReleaseList := &helmv2.HelmReleaseList{}
 	listOpts := []client.ListOption{}
@@ -67,47 +110,27 @@ func CheckFlux(namespace string, debug bool) error {
 
 	if debug {
 		if namespace == "" {
-			fmt.Printf("  Operation: List HelmReleases (all namespaces)\n")
+			fmt.Fprintf(os.Stderr, "  Operation: List HelmReleases (all namespaces)\n")
 		} else {
-			fmt.Printf("  Operation: List HelmReleases (namespace: %s)\n", namespace)
+			fmt.Fprintf(os.Stderr, "  Operation: List HelmReleases (namespace: %s)\n", namespace)
 		}
 	}
 
-	err = k8sClient.List(ctx, helmReleaseList, listOpts...)
+	result.Attempts, err = retry.Do(ctx, common.IsRetryableAPIError, func() error {
+		return k8sClient.List(ctx, helmReleaseList, listOpts...)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to list HelmReleases: %v", err)
+		return result, fmt.Errorf("failed to list HelmReleases: %v", err)
 	}
 
 	if debug {
-		fmt.Printf("[DEBUG] Kubernetes API Response:\n")
-		fmt.Printf("  HelmReleases found: %d\n", len(helmReleaseList.Items))
+		fmt.Fprintf(os.Stderr, "[DEBUG] Kubernetes API Response:\n")
+		fmt.Fprintf(os.Stderr, "  HelmReleases found: %d\n", len(helmReleaseList.Items))
 	}
 
-	fmt.Printf("\n\033[1mHelmReleases:\033[0m\n")
 	for _, hr := range helmReleaseList.Items {
-		totalResources++
-		resourceName := fmt.Sprintf("%s/%s", hr.Namespace, hr.Name)
-		ready := false
-
-		// Check Ready condition
-		for _, condition := range hr.Status.Conditions {
-			if condition.Type == "Ready" {
-				if condition.Status == metav1.ConditionTrue {
-					ready = true
-					readyResources++
-					fmt.Printf("%s \033[32m🟢 Ready\033[0m (revision: %s)\n", resourceName, hr.Status.LastAttemptedRevision)
-				} else {
-					failedResources = append(failedResources, fmt.Sprintf("HelmRelease %s: %s", resourceName, condition.Message))
-					fmt.Printf("%s \033[31m🔴 Not Ready\033[0m - %s\n", resourceName, condition.Message)
-				}
-				break
-			}
-		}
-
-		if !ready && len(hr.Status.Conditions) == 0 {
-			failedResources = append(failedResources, fmt.Sprintf("HelmRelease %s: No conditions set", resourceName))
-			fmt.Printf("%s \033[33m⚠️  Unknown\033[0m - No conditions set\n", resourceName)
-		}
+		result.Findings = append(result.Findings,
+			readyFinding("HelmRelease", hr.ObjectMeta, hr.Status.Conditions, hr.Status.LastAttemptedRevision))
 	}
 
 	// Check Kustomizations
@@ -115,62 +138,75 @@ func CheckFlux(namespace string, debug bool) error {
 
 	if debug {
 		if namespace == "" {
-			fmt.Printf("  Operation: List Kustomizations (all namespaces)\n")
+			fmt.Fprintf(os.Stderr, "  Operation: List Kustomizations (all namespaces)\n")
 		} else {
-			fmt.Printf("  Operation: List Kustomizations (namespace: %s)\n", namespace)
+			fmt.Fprintf(os.Stderr, "  Operation: List Kustomizations (namespace: %s)\n", namespace)
 		}
 	}
 
-	err = k8sClient.List(ctx, kustomizationList, listOpts...)
+	attempts, err := retry.Do(ctx, common.IsRetryableAPIError, func() error {
+		return k8sClient.List(ctx, kustomizationList, listOpts...)
+	})
+	result.Attempts = max(result.Attempts, attempts)
 	if err != nil {
-		return fmt.Errorf("failed to list Kustomizations: %v", err)
+		return result, fmt.Errorf("failed to list Kustomizations: %v", err)
 	}
 
 	if debug {
-		fmt.Printf("[DEBUG] Kubernetes API Response:\n")
-		fmt.Printf("  Kustomizations found: %d\n\n", len(kustomizationList.Items))
+		fmt.Fprintf(os.Stderr, "[DEBUG] Kubernetes API Response:\n")
+		fmt.Fprintf(os.Stderr, "  Kustomizations found: %d\n\n", len(kustomizationList.Items))
 	}
 
-	fmt.Printf("\n\033[1mKustomizations:\033[0m\n")
 	for _, ks := range kustomizationList.Items {
-		totalResources++
-		resourceName := fmt.Sprintf("%s/%s", ks.Namespace, ks.Name)
-		ready := false
-
-		// Check Ready condition
-		for _, condition := range ks.Status.Conditions {
-			if condition.Type == "Ready" {
-				if condition.Status == metav1.ConditionTrue {
-					ready = true
-					readyResources++
-					fmt.Printf("%s \033[32m🟢 Ready\033[0m (revision: %s)\n", resourceName, ks.Status.LastAppliedRevision)
-				} else {
-					failedResources = append(failedResources, fmt.Sprintf("Kustomization %s: %s", resourceName, condition.Message))
-					fmt.Printf("%s \033[31m🔴 Not Ready\033[0m - %s\n", resourceName, condition.Message)
-				}
-				break
-			}
-		}
-
-		if !ready && len(ks.Status.Conditions) == 0 {
-			failedResources = append(failedResources, fmt.Sprintf("Kustomization %s: No conditions set", resourceName))
-			fmt.Printf("%s \033[33m⚠️  Unknown\033[0m - No conditions set\n", resourceName)
-		}
+		result.Findings = append(result.Findings,
+			readyFinding("Kustomization", ks.ObjectMeta, ks.Status.Conditions, ks.Status.LastAppliedRevision))
 	}
 
-	fmt.Printf("\n\033[1mSummary:\033[0m %d/%d resources Ready\n", readyResources, totalResources)
+	failedResources := len(result.Failed())
+	if failedResources > 0 {
+		result.Message = fmt.Sprintf("%d resources not Ready", failedResources)
+		return result, nil
+	}
 
-	if len(failedResources) > 0 {
-		fmt.Printf("\033[31m\nFailed resources:\033[0m\n")
-		for _, resource := range failedResources {
-			fmt.Printf("  - %s\n", resource)
-		}
-		return fmt.Errorf("%d resources not Ready", len(failedResources))
+	result.Passed = true
+	if len(result.Findings) == 0 {
+		result.Message = "No Flux resources found"
+	} else {
+		result.Message = "All HelmReleases and Kustomizations are Ready"
 	}
+	return result, nil
+}
 
-	if totalResources == 0 {
-		fmt.Printf("\033[33mNo Flux resources found\033[0m\n")
+// readyFinding describes the state of a Flux resource based on its Ready condition
+func readyFinding(kind string, meta metav1.ObjectMeta, conditions []metav1.Condition, revision string) checker.Finding {
+	finding := checker.Finding{
+		Kind:      kind,
+		Namespace: meta.Namespace,
+		Name:      meta.Name,
+		Status:    "Unknown",
+		Message:   "No Ready condition set",
+		Revision:  revision,
+		CreatedAt: meta.CreationTimestamp.Time,
+	}
+
+	if len(conditions) == 0 {
+		finding.Message = "No conditions set"
+	}
+
+	for _, condition := range conditions {
+		if condition.Type == "Ready" {
+			finding.Healthy = condition.Status == metav1.ConditionTrue
+			if finding.Healthy {
+				finding.Status = "Ready"
+			} else {
+				finding.Status = "Not Ready"
+			}
+			finding.Reason = condition.Reason
+			finding.Message = condition.Message
+			finding.LastTransition = condition.LastTransitionTime.Time
+			break
+		}
 	}
 
-	return nil
+	return finding
 }
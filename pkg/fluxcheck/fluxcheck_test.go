@@ -1,9 +1,13 @@
 package fluxcheck
 
 import (
+	"context"
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestCheckFluxWithInvalidConfig(t *testing.T) {
@@ -18,7 +22,7 @@ func TestCheckFluxWithInvalidConfig(t *testing.T) {
 	// Set invalid kubeconfig path
 	os.Setenv("KUBECONFIG", "/nonexistent/path/to/kubeconfig")
 
-	err := CheckFlux("", false)
+	_, err := CheckFlux(context.Background(), checker.KubeOptions{}, "", checker.Retry{}, false)
 	if err == nil {
 		t.Error("Expected error for invalid kubeconfig, got nil")
 	}
@@ -27,3 +31,91 @@ func TestCheckFluxWithInvalidConfig(t *testing.T) {
 		t.Errorf("Expected 'failed to build config' error, got: %v", err)
 	}
 }
+
+func TestCheckerWithInvalidConfig(t *testing.T) {
+	originalKubeConfig := os.Getenv("KUBECONFIG")
+	defer os.Setenv("KUBECONFIG", originalKubeConfig)
+
+	os.Setenv("KUBECONFIG", "/nonexistent/path/to/kubeconfig")
+
+	c := NewChecker(checker.Options{})
+	if c.Category() != checker.CategoryFlux {
+		t.Errorf("Expected category '%s', got '%s'", checker.CategoryFlux, c.Category())
+	}
+
+	results := c.Run(context.Background())
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+
+	if results[0].Passed {
+		t.Error("Expected result to fail for invalid kubeconfig")
+	}
+
+	if results[0].Name != "Flux Resources" {
+		t.Errorf("Expected name 'Flux Resources', got '%s'", results[0].Name)
+	}
+}
+
+func TestReadyFinding(t *testing.T) {
+	meta := metav1.ObjectMeta{Namespace: "flux-system", Name: "app"}
+
+	tests := []struct {
+		name            string
+		conditions      []metav1.Condition
+		expectedHealthy bool
+		expectedStatus  string
+		expectedMessage string
+	}{
+		{
+			name:            "ready",
+			conditions:      []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue, Reason: "ReconciliationSucceeded", Message: "Applied revision"}},
+			expectedHealthy: true,
+			expectedStatus:  "Ready",
+			expectedMessage: "Applied revision",
+		},
+		{
+			name:            "not ready",
+			conditions:      []metav1.Condition{{Type: "Ready", Status: metav1.ConditionFalse, Reason: "InstallFailed", Message: "install retries exhausted"}},
+			expectedHealthy: false,
+			expectedStatus:  "Not Ready",
+			expectedMessage: "install retries exhausted",
+		},
+		{
+			name:            "no conditions",
+			conditions:      nil,
+			expectedHealthy: false,
+			expectedStatus:  "Unknown",
+			expectedMessage: "No conditions set",
+		},
+		{
+			name:            "no ready condition",
+			conditions:      []metav1.Condition{{Type: "Reconciling", Status: metav1.ConditionTrue}},
+			expectedHealthy: false,
+			expectedStatus:  "Unknown",
+			expectedMessage: "No Ready condition set",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finding := readyFinding("HelmRelease", meta, tt.conditions, "1.0.0")
+
+			if finding.Kind != "HelmRelease" || finding.ObjectName() != "flux-system/app" {
+				t.Errorf("Unexpected object %s %s", finding.Kind, finding.ObjectName())
+			}
+			if finding.Healthy != tt.expectedHealthy {
+				t.Errorf("Expected healthy %v, got %v", tt.expectedHealthy, finding.Healthy)
+			}
+			if finding.Status != tt.expectedStatus {
+				t.Errorf("Expected status '%s', got '%s'", tt.expectedStatus, finding.Status)
+			}
+			if finding.Message != tt.expectedMessage {
+				t.Errorf("Expected message '%s', got '%s'", tt.expectedMessage, finding.Message)
+			}
+			if finding.Revision != "1.0.0" {
+				t.Errorf("Expected revision '1.0.0', got '%s'", finding.Revision)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package gatecheck
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/eumel8/clustercheck/pkg/capicheck"
+	"github.com/eumel8/clustercheck/pkg/checker"
+)
+
+// RunCAPI runs the gate check with the given check names against the
+// workload clusters of the Cluster API management cluster of opts.Kube, at
+// most parallelism clusters at a time. The provisioning state of each
+// cluster is reported and scored with its checks. Clusters without
+// kubeconfig Secret are not checked.
+func RunCAPI(ctx context.Context, opts checker.Options, parallelism int, names ...string) (*FleetResult, error) {
+	management, err := capicheck.NewManagement(opts.Kube, opts.Retry(), opts.Debug)
+	if err != nil {
+		return nil, err
+	}
+	clusters, err := management.Clusters(ctx, opts.CAPINamespace, opts.CAPISelector)
+	if err != nil {
+		return nil, err
+	}
+
+	// the kubeconfigs of the workload clusters are only kept during the run
+	dir, err := os.MkdirTemp("", "clustercheck-capi-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubeconfig directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	targets := []FleetTarget{}
+	for i, cluster := range clusters {
+		target := FleetTarget{
+			Name:    cluster.ObjectName(),
+			Results: []CheckResult{cluster.Result()},
+		}
+
+		kubeconfig, err := management.Kubeconfig(ctx, cluster)
+		if err == nil {
+			path := filepath.Join(dir, fmt.Sprintf("%d-%s", i, cluster.Name))
+			if err = os.WriteFile(path, kubeconfig, 0600); err != nil {
+				err = fmt.Errorf("failed to write kubeconfig: %v", err)
+			}
+			// the cluster name is the short cluster label of the Prometheus checks
+			target.Opts = opts
+			target.Opts.Kube = checker.KubeOptions{Kubeconfig: path, ClusterName: cluster.Name}
+		}
+		if err != nil {
+			target.Skip = true
+			target.Results = append(target.Results, CheckResult{
+				Name:     "Cluster API Kubeconfig",
+				Category: checker.CategoryCAPI,
+				Target:   cluster.ObjectName(),
+				Message:  err.Error(),
+			})
+		}
+		targets = append(targets, target)
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no Cluster API clusters found")
+	}
+	return RunTargets(ctx, targets, parallelism, names...)
+}
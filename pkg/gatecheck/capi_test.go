@@ -0,0 +1,101 @@
+package gatecheck
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// kubeconfigChecker passes if its kubeconfig exists and records the cluster names
+type kubeconfigChecker struct {
+	kube  checker.KubeOptions
+	mu    *sync.Mutex
+	names *[]string
+}
+
+func (c *kubeconfigChecker) Name() string     { return "Kubeconfig" }
+func (c *kubeconfigChecker) Category() string { return "static" }
+func (c *kubeconfigChecker) Run(ctx context.Context) []checker.Result {
+	c.mu.Lock()
+	*c.names = append(*c.names, c.kube.ClusterName)
+	c.mu.Unlock()
+	_, err := os.Stat(c.kube.Kubeconfig)
+	return []checker.Result{{Name: "KUBECONFIG", Category: "static", Passed: err == nil}}
+}
+
+func TestRunCAPI(t *testing.T) {
+	kubeconfig := base64.StdEncoding.EncodeToString([]byte("apiVersion: v1\nkind: Config\n"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/apis/cluster.x-k8s.io/v1beta2":
+			w.Write([]byte(`{"kind":"APIResourceList","apiVersion":"v1","groupVersion":"cluster.x-k8s.io/v1beta2","resources":[{"name":"clusters","namespaced":true,"kind":"Cluster","verbs":["list"]}]}`))
+		case "/apis/cluster.x-k8s.io/v1beta2/namespaces/tenants/clusters":
+			w.Write([]byte(`{"apiVersion":"cluster.x-k8s.io/v1beta2","kind":"ClusterList","metadata":{},"items":[
+{"apiVersion":"cluster.x-k8s.io/v1beta2","kind":"Cluster","metadata":{"name":"prod","namespace":"tenants"},"status":{"phase":"Provisioned"}},
+{"apiVersion":"cluster.x-k8s.io/v1beta2","kind":"Cluster","metadata":{"name":"dev","namespace":"tenants"},"status":{"phase":"Provisioning"}}]}`))
+		case "/api/v1/namespaces/tenants/secrets/prod-kubeconfig":
+			w.Write([]byte(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"prod-kubeconfig"},"data":{"value":"` + kubeconfig + `"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"apiVersion":"v1","kind":"Status","status":"Failure","reason":"NotFound","code":404}`))
+		}
+	}))
+	defer server.Close()
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["management"] = &clientcmdapi.Cluster{Server: server.URL}
+	config.AuthInfos["management"] = &clientcmdapi.AuthInfo{}
+	config.Contexts["management"] = &clientcmdapi.Context{Cluster: "management", AuthInfo: "management"}
+	config.CurrentContext = "management"
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+	t.Setenv("KUBECONFIG", path)
+
+	var mu sync.Mutex
+	names := []string{}
+	checker.Register("test-capi", 104, func(opts checker.Options) checker.Checker {
+		return &kubeconfigChecker{kube: opts.Kube, mu: &mu, names: &names}
+	})
+
+	result, err := RunCAPI(context.Background(), checker.Options{CAPINamespace: "tenants"}, 2, "test-capi")
+	if err == nil {
+		t.Error("Expected the provisioning cluster to fail the fleet, got nil")
+	}
+	if result == nil {
+		t.Fatalf("RunCAPI() returned error: %v", err)
+	}
+	if result.TotalClusters != 2 || result.PassedClusters != 1 {
+		t.Fatalf("Expected 1 of 2 clusters passed, got %d of %d", result.PassedClusters, result.TotalClusters)
+	}
+
+	prod, dev := result.Clusters[0], result.Clusters[1]
+	if prod.Context != "tenants/prod" || !prod.OverallPassed || len(prod.CheckResults) != 2 || prod.CheckResults[0].Name != "Cluster API Provisioning" {
+		t.Errorf("Expected the provisioning state and checks of tenants/prod, got %+v", prod)
+	}
+	if dev.Context != "tenants/dev" || dev.OverallPassed || len(dev.CheckResults) != 2 {
+		t.Fatalf("Expected the failed provisioning state and kubeconfig of tenants/dev, got %+v", dev)
+	}
+	if kubeconfig := dev.CheckResults[1]; kubeconfig.Name != "Cluster API Kubeconfig" || !strings.Contains(kubeconfig.Message, "tenants/dev-kubeconfig") {
+		t.Errorf("Expected the missing kubeconfig Secret of dev, got %+v", kubeconfig)
+	}
+	if len(names) != 1 || names[0] != "prod" {
+		t.Errorf("Expected only prod to be checked with its cluster name, got %v", names)
+	}
+
+	if _, err := RunCAPI(context.Background(), checker.Options{CAPINamespace: "empty"}, 2, "test-capi"); err == nil {
+		t.Error("Expected error without Cluster API clusters")
+	}
+}
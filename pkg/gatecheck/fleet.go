@@ -0,0 +1,146 @@
+package gatecheck
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+)
+
+// DefaultFleetParallelism is the default number of clusters checked concurrently
+const DefaultFleetParallelism = 4
+
+// FleetResult represents the gate check results of several clusters
+type FleetResult struct {
+	StartedAt      time.Time
+	Duration       time.Duration
+	TotalClusters  int
+	PassedClusters int
+	FailedClusters int
+	// Clusters holds the results in the order of the contexts
+	Clusters      []*GateCheckResult
+	OverallPassed bool
+}
+
+// Checks returns the names of the checks of all clusters in run order
+func (r *FleetResult) Checks() []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, cluster := range r.Clusters {
+		for _, check := range cluster.CheckResults {
+			if !seen[check.Name] {
+				seen[check.Name] = true
+				names = append(names, check.Name)
+			}
+		}
+	}
+	return names
+}
+
+// Failed returns the results of the clusters which did not pass the gate
+func (r *FleetResult) Failed() []*GateCheckResult {
+	failed := []*GateCheckResult{}
+	for _, cluster := range r.Clusters {
+		if !cluster.OverallPassed {
+			failed = append(failed, cluster)
+		}
+	}
+	return failed
+}
+
+// FleetTarget is a cluster of a fleet run
+type FleetTarget struct {
+	// Name is the displayed name of the cluster
+	Name string
+	// Opts are the options of the checks of the cluster
+	Opts checker.Options
+	// Results are reported and scored before the results of the checks, e.g.
+	// the provisioning state of the cluster
+	Results []CheckResult
+	// Skip skips the checks, e.g. of a cluster which is not reachable
+	Skip bool
+}
+
+// RunFleet runs the gate check with the given check names against each kube
+// context, at most parallelism clusters at a time. The fleet passes if all
+// clusters pass.
+func RunFleet(ctx context.Context, opts checker.Options, contexts []string, parallelism int, names ...string) (*FleetResult, error) {
+	targets := []FleetTarget{}
+	for _, kubeContext := range contexts {
+		target := FleetTarget{Name: kubeContext, Opts: opts}
+		target.Opts.Kube.Context = kubeContext
+		targets = append(targets, target)
+	}
+	return RunTargets(ctx, targets, parallelism, names...)
+}
+
+// RunTargets runs the gate check with the given check names against each
+// target, at most parallelism clusters at a time. The fleet passes if all
+// clusters pass.
+func RunTargets(ctx context.Context, targets []FleetTarget, parallelism int, names ...string) (*FleetResult, error) {
+	result := &FleetResult{
+		StartedAt:     time.Now(),
+		TotalClusters: len(targets),
+		Clusters:      make([]*GateCheckResult, len(targets)),
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	// fail early on unknown check names instead of once per cluster
+	if _, err := checker.Select(checker.Options{}, names...); err != nil {
+		return result, err
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallelism)
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			result.Clusters[i] = runTarget(ctx, target, names...)
+		}()
+	}
+	wg.Wait()
+
+	for _, cluster := range result.Clusters {
+		if cluster.OverallPassed {
+			result.PassedClusters++
+		} else {
+			result.FailedClusters++
+		}
+	}
+	result.OverallPassed = result.TotalClusters > 0 && result.FailedClusters == 0
+	result.Duration = time.Since(result.StartedAt)
+
+	if !result.OverallPassed {
+		return result, fmt.Errorf("%d of %d clusters failed the gate check", result.FailedClusters, result.TotalClusters)
+	}
+	return result, nil
+}
+
+// runTarget runs the gate check of a single fleet target
+func runTarget(ctx context.Context, target FleetTarget, names ...string) *GateCheckResult {
+	cluster := &GateCheckResult{
+		Context:      target.Name,
+		StartedAt:    time.Now(),
+		CheckResults: []CheckResult{},
+	}
+	for _, check := range target.Results {
+		cluster.add(check)
+	}
+
+	if !target.Skip {
+		checks, _ := Run(ctx, target.Opts, names...)
+		for _, check := range checks.CheckResults {
+			cluster.add(check)
+		}
+	}
+
+	cluster.score()
+	return cluster
+}
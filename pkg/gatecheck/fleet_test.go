@@ -0,0 +1,96 @@
+package gatecheck
+
+import (
+	"context"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// contextChecker fails the checks of the kube context "broken" and records
+// the maximum number of concurrent runs
+type contextChecker struct {
+	context string
+	running *atomic.Int32
+	max     *atomic.Int32
+}
+
+func (c *contextChecker) Name() string     { return "Context" }
+func (c *contextChecker) Category() string { return "static" }
+func (c *contextChecker) Run(ctx context.Context) []checker.Result {
+	running := c.running.Add(1)
+	defer c.running.Add(-1)
+	for {
+		max := c.max.Load()
+		if running <= max || c.max.CompareAndSwap(max, running) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	passed := c.context != "broken"
+	results := []checker.Result{{Name: "API", Category: "static", Passed: passed}}
+	if c.context == "dev" {
+		results = append(results, checker.Result{Name: "DEV_ONLY", Category: "static", Passed: true})
+	}
+	return results
+}
+
+func TestRunFleet(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["test"] = &clientcmdapi.Cluster{Server: "https://test.example.com"}
+	for _, name := range []string{"prod", "dev", "broken"} {
+		config.Contexts[name] = &clientcmdapi.Context{Cluster: "test"}
+	}
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+	t.Setenv("KUBECONFIG", path)
+
+	running, max := &atomic.Int32{}, &atomic.Int32{}
+	checker.Register("test-fleet", 103, func(opts checker.Options) checker.Checker {
+		return &contextChecker{context: opts.Kube.Context, running: running, max: max}
+	})
+
+	result, err := RunFleet(context.Background(), checker.Options{}, []string{"prod", "dev", "broken"}, 2, "test-fleet")
+	if err == nil {
+		t.Error("Expected the failed cluster to fail the fleet, got nil")
+	}
+	if result.TotalClusters != 3 || result.PassedClusters != 2 || result.FailedClusters != 1 || result.OverallPassed {
+		t.Errorf("Expected 2 of 3 clusters passed, got %d of %d (%d failed)",
+			result.PassedClusters, result.TotalClusters, result.FailedClusters)
+	}
+	if max.Load() > 2 {
+		t.Errorf("Expected at most 2 concurrent clusters, got %d", max.Load())
+	}
+
+	for i, name := range []string{"prod", "dev", "broken"} {
+		if result.Clusters[i].Context != name {
+			t.Errorf("Expected cluster %d to be %s, got %s", i, name, result.Clusters[i].Context)
+		}
+	}
+	if failed := result.Failed(); len(failed) != 1 || failed[0].Context != "broken" {
+		t.Errorf("Expected the failed cluster broken, got %v", failed)
+	}
+	if checks := result.Checks(); len(checks) != 2 || checks[0] != "API" || checks[1] != "DEV_ONLY" {
+		t.Errorf("Expected checks [API DEV_ONLY], got %v", checks)
+	}
+	if _, ok := result.Clusters[0].Check("DEV_ONLY"); ok {
+		t.Error("Expected no DEV_ONLY check on prod")
+	}
+
+	result, err = RunFleet(context.Background(), checker.Options{}, []string{"prod", "dev"}, 2, "test-fleet")
+	if err != nil || !result.OverallPassed {
+		t.Errorf("Expected the fleet to pass, got %v", err)
+	}
+
+	if _, err := RunFleet(context.Background(), checker.Options{}, []string{"prod"}, 2, "unknown"); err == nil {
+		t.Error("Expected error for an unknown check")
+	}
+}
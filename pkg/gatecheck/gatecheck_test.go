@@ -1,9 +1,15 @@
 package gatecheck
 
 import (
+	"context"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
 func TestCheckResult(t *testing.T) {
@@ -88,3 +94,133 @@ func TestGateCheckWithInvalidConfig(t *testing.T) {
 		t.Logf("Got expected error: %v", err)
 	}
 }
+
+func TestQualityGate(t *testing.T) {
+	tests := []struct {
+		score    float64
+		expected string
+	}{
+		{100, "EXCELLENT"},
+		{90, "EXCELLENT"},
+		{85, "GOOD"},
+		{80, "GOOD"},
+		{60, "FAIR"},
+		{59.9, "POOR"},
+		{0, "POOR"},
+	}
+
+	for _, tt := range tests {
+		result := GateCheckResult{HealthScore: tt.score}
+		tier, decision := result.QualityGate()
+		if tier != tt.expected {
+			t.Errorf("Score %.1f: expected tier '%s', got '%s'", tt.score, tt.expected, tier)
+		}
+		if decision == "" {
+			t.Errorf("Score %.1f: expected decision, got empty string", tt.score)
+		}
+	}
+}
+
+func TestCategories(t *testing.T) {
+	result := GateCheckResult{
+		CheckResults: []CheckResult{
+			{Name: "Pod Health", Category: "pods"},
+			{Name: "APISERVER", Category: "prometheus"},
+			{Name: "KUBELET", Category: "prometheus"},
+		},
+	}
+
+	categories := result.Categories()
+	if len(categories) != 2 || categories[0] != "pods" || categories[1] != "prometheus" {
+		t.Errorf("Expected categories [pods prometheus], got %v", categories)
+	}
+
+	if len(result.Results("prometheus")) != 2 {
+		t.Errorf("Expected 2 prometheus results, got %d", len(result.Results("prometheus")))
+	}
+}
+
+// staticChecker returns a fixed list of results
+type staticChecker struct {
+	results []checker.Result
+}
+
+func (c *staticChecker) Name() string     { return "Static" }
+func (c *staticChecker) Category() string { return "static" }
+func (c *staticChecker) Run(ctx context.Context) []checker.Result {
+	return c.results
+}
+
+func TestRunWarnings(t *testing.T) {
+	checker.Register("test-warnings", 100, func(opts checker.Options) checker.Checker {
+		return &staticChecker{results: []checker.Result{
+			{Name: "CRITICAL_OK", Category: "static", Passed: true, Severity: checker.SeverityCritical},
+			{Name: "WARNING_OK", Category: "static", Passed: true, Severity: checker.SeverityWarning},
+			{Name: "WARNING_FAIL", Category: "static", Passed: false, Severity: checker.SeverityWarning},
+		}}
+	})
+
+	result, err := Run(context.Background(), checker.Options{}, "test-warnings")
+	if err != nil {
+		t.Fatalf("Expected failed warnings not to fail the gate, got: %v", err)
+	}
+
+	if result.TotalChecks != 2 || result.PassedChecks != 2 || result.FailedChecks != 0 {
+		t.Errorf("Expected 2 of 2 checks passed, got %d of %d (%d failed)",
+			result.PassedChecks, result.TotalChecks, result.FailedChecks)
+	}
+	if result.WarningChecks != 1 {
+		t.Errorf("Expected 1 warning, got %d", result.WarningChecks)
+	}
+	if result.HealthScore != 100 {
+		t.Errorf("Expected HealthScore 100, got %.1f", result.HealthScore)
+	}
+	if len(result.CheckResults) != 3 {
+		t.Errorf("Expected 3 check results, got %d", len(result.CheckResults))
+	}
+}
+
+func TestRunTimedOut(t *testing.T) {
+	checker.Register("test-timeouts", 101, func(opts checker.Options) checker.Checker {
+		return &staticChecker{results: []checker.Result{
+			{Name: "OK", Category: "static", Passed: true},
+			{Name: "SLOW", Category: "static", Passed: false, TimedOut: true, Message: "Query timed out after 30s"},
+		}}
+	})
+
+	result, err := Run(context.Background(), checker.Options{}, "test-timeouts")
+	if err == nil {
+		t.Error("Expected timed out check to fail the gate, got nil")
+	}
+	if result.TimedOutChecks != 1 || result.FailedChecks != 1 || result.TotalChecks != 2 {
+		t.Errorf("Expected 1 timed out of 1 failed checks, got %d timed out, %d failed, %d total",
+			result.TimedOutChecks, result.FailedChecks, result.TotalChecks)
+	}
+}
+
+func TestRunContext(t *testing.T) {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["test"] = &clientcmdapi.Cluster{Server: "https://test.example.com"}
+	config.Contexts["prod"] = &clientcmdapi.Context{Cluster: "test"}
+	config.Contexts["dev"] = &clientcmdapi.Context{Cluster: "test"}
+	config.CurrentContext = "prod"
+	path := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+	t.Setenv("KUBECONFIG", path)
+
+	checker.Register("test-context", 102, func(opts checker.Options) checker.Checker {
+		return &staticChecker{results: []checker.Result{{Name: "OK", Category: "static", Passed: true}}}
+	})
+
+	result, _ := Run(context.Background(), checker.Options{}, "test-context")
+	if result.Context != "prod" {
+		t.Errorf("Expected the current context prod of KUBECONFIG, got %s", result.Context)
+	}
+
+	result, _ = Run(context.Background(), checker.Options{Kube: checker.KubeOptions{Context: "dev"}}, "test-context")
+	if result.Context != "dev" {
+		t.Errorf("Expected the context dev of the options, got %s", result.Context)
+	}
+}
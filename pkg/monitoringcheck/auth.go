@@ -0,0 +1,203 @@
+package monitoringcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// Authentication types of a Prometheus endpoint
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthOAuth2 = "oauth2"
+	AuthNone   = "none"
+)
+
+// AuthConfig configures the authentication of a Prometheus endpoint
+type AuthConfig struct {
+	// Type is basic (default), bearer, oauth2 or none
+	Type string `json:"type,omitempty"`
+	// Username and Password of basic auth. Without both PROM_USER and
+	// PROM_PASS or the Bitwarden item are used.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Token is a static bearer token
+	Token string `json:"token,omitempty"`
+	// TokenFile is read on every request, so rotated tokens of mounted
+	// secrets are picked up
+	TokenFile string `json:"tokenFile,omitempty"`
+	// OAuth2 configures the client credentials flow
+	OAuth2 *OAuth2Config `json:"oauth2,omitempty"`
+}
+
+// OAuth2Config configures the OAuth2 client credentials flow. The token is
+// cached until it expires.
+type OAuth2Config struct {
+	TokenURL         string   `json:"tokenURL"`
+	ClientID         string   `json:"clientID"`
+	ClientSecret     string   `json:"clientSecret,omitempty"`
+	ClientSecretFile string   `json:"clientSecretFile,omitempty"`
+	Scopes           []string `json:"scopes,omitempty"`
+}
+
+// Validate checks that the settings of the authentication type are complete
+func (a AuthConfig) Validate() error {
+	switch a.Type {
+	case "", AuthBasic, AuthNone:
+	case AuthBearer:
+		if a.Token == "" && a.TokenFile == "" {
+			return fmt.Errorf("bearer auth requires a token or tokenFile")
+		}
+		if a.Token != "" && a.TokenFile != "" {
+			return fmt.Errorf("bearer auth takes either a token or tokenFile")
+		}
+	case AuthOAuth2:
+		if a.OAuth2 == nil || a.OAuth2.TokenURL == "" || a.OAuth2.ClientID == "" {
+			return fmt.Errorf("oauth2 auth requires tokenURL and clientID")
+		}
+		if a.OAuth2.ClientSecret != "" && a.OAuth2.ClientSecretFile != "" {
+			return fmt.Errorf("oauth2 auth takes either a clientSecret or clientSecretFile")
+		}
+	default:
+		return fmt.Errorf("unknown auth type %q", a.Type)
+	}
+	return nil
+}
+
+// needsCredentials reports whether the basic auth credentials are taken from
+// PROM_USER and PROM_PASS or Bitwarden
+func (a AuthConfig) needsCredentials() bool {
+	return (a.Type == "" || a.Type == AuthBasic) && a.Username == "" && a.Password == ""
+}
+
+// Authenticator sets the credentials of a Prometheus API request
+type Authenticator interface {
+	// Authenticate adds the credentials to the request
+	Authenticate(req *http.Request) error
+	// String describes the authentication for the debug output
+	String() string
+}
+
+// NewAuthenticator creates the Authenticator of the config. Token requests of
+// the OAuth2 flow are sent with httpClient.
+func NewAuthenticator(config AuthConfig, httpClient *http.Client) (Authenticator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch config.Type {
+	case AuthNone:
+		return noAuth{}, nil
+	case AuthBearer:
+		return &bearerAuth{token: config.Token, tokenFile: config.TokenFile}, nil
+	case AuthOAuth2:
+		secret := config.OAuth2.ClientSecret
+		if config.OAuth2.ClientSecretFile != "" {
+			data, err := os.ReadFile(config.OAuth2.ClientSecretFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read client secret: %v", err)
+			}
+			secret = strings.TrimSpace(string(data))
+		}
+		return &oauth2Auth{
+			config: &clientcredentials.Config{
+				ClientID:     config.OAuth2.ClientID,
+				ClientSecret: secret,
+				TokenURL:     config.OAuth2.TokenURL,
+				Scopes:       config.OAuth2.Scopes,
+			},
+			httpClient: httpClient,
+		}, nil
+	default:
+		return &basicAuth{username: config.Username, password: config.Password}, nil
+	}
+}
+
+// noAuth sends requests without credentials
+type noAuth struct{}
+
+func (noAuth) Authenticate(req *http.Request) error { return nil }
+
+func (noAuth) String() string { return "None" }
+
+// basicAuth sets HTTP basic auth credentials
+type basicAuth struct {
+	username string
+	password string
+}
+
+func (a *basicAuth) Authenticate(req *http.Request) error {
+	req.SetBasicAuth(a.username, a.password)
+	return nil
+}
+
+func (a *basicAuth) String() string {
+	return fmt.Sprintf("Basic (username: %s)", a.username)
+}
+
+// bearerAuth sets a static bearer token or the token read from a file
+type bearerAuth struct {
+	token     string
+	tokenFile string
+}
+
+func (a *bearerAuth) Authenticate(req *http.Request) error {
+	token := a.token
+	if a.tokenFile != "" {
+		data, err := os.ReadFile(a.tokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read bearer token: %v", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		return fmt.Errorf("bearer token is empty")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (a *bearerAuth) String() string {
+	if a.tokenFile != "" {
+		return fmt.Sprintf("Bearer (token file: %s)", a.tokenFile)
+	}
+	return "Bearer (static token)"
+}
+
+// oauth2Auth fetches an access token with the client credentials flow and
+// caches it until it expires
+type oauth2Auth struct {
+	config     *clientcredentials.Config
+	httpClient *http.Client
+
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+func (a *oauth2Auth) Authenticate(req *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Valid is false for a missing token and shortly before the expiry
+	if !a.token.Valid() {
+		ctx := context.WithValue(req.Context(), oauth2.HTTPClient, a.httpClient)
+		token, err := a.config.Token(ctx)
+		if err != nil {
+			return err
+		}
+		a.token = token
+	}
+	a.token.SetAuthHeader(req)
+	return nil
+}
+
+func (a *oauth2Auth) String() string {
+	return fmt.Sprintf("OAuth2 client credentials (client: %s)", a.config.ClientID)
+}
@@ -0,0 +1,169 @@
+package monitoringcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// authServer returns a Prometheus server which only accepts the given Authorization header
+func authServer(t *testing.T, header *atomic.Value) *httptest.Server {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != header.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"status":"error","error":"unauthorized"}`))
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestBearerAuth(t *testing.T) {
+	var header atomic.Value
+	header.Store("Bearer static-token")
+	server := authServer(t, &header)
+
+	auth, err := NewAuthenticator(AuthConfig{Type: AuthBearer, Token: "static-token"}, sharedHTTPClient)
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	client := &Client{URL: server.URL, Auth: auth, HTTPClient: sharedHTTPClient}
+	if _, err := client.Query(context.Background(), "up"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestBearerAuthTokenFile(t *testing.T) {
+	var header atomic.Value
+	header.Store("Bearer token-1")
+	server := authServer(t, &header)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenFile, []byte("token-1\n"), 0600)
+
+	auth, err := NewAuthenticator(AuthConfig{Type: AuthBearer, TokenFile: tokenFile}, sharedHTTPClient)
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	client := &Client{URL: server.URL, Auth: auth, HTTPClient: sharedHTTPClient}
+	if _, err := client.Query(context.Background(), "up"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// a rotated token is read on the next request
+	header.Store("Bearer token-2")
+	os.WriteFile(tokenFile, []byte("token-2\n"), 0600)
+	if _, err := client.Query(context.Background(), "up"); err != nil {
+		t.Errorf("Expected the rotated token to be used, got: %v", err)
+	}
+
+	os.Remove(tokenFile)
+	if _, err := client.Query(context.Background(), "up"); err == nil || !strings.Contains(err.Error(), "failed to read bearer token") {
+		t.Errorf("Expected error for missing token file, got: %v", err)
+	}
+}
+
+func TestOAuth2Auth(t *testing.T) {
+	var tokenRequests int32
+	tokenServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenRequests, 1)
+		r.ParseForm()
+		user, pass, _ := r.BasicAuth()
+		if r.Form.Get("grant_type") != "client_credentials" || user != "clustercheck" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		if r.Form.Get("scope") != "metrics" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_scope"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"access-%d","token_type":"Bearer","expires_in":3600}`, n)
+	}))
+	defer tokenServer.Close()
+
+	var header atomic.Value
+	header.Store("Bearer access-1")
+	server := authServer(t, &header)
+
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	os.WriteFile(secretFile, []byte("secret\n"), 0600)
+
+	auth, err := NewAuthenticator(AuthConfig{Type: AuthOAuth2, OAuth2: &OAuth2Config{
+		TokenURL:         tokenServer.URL,
+		ClientID:         "clustercheck",
+		ClientSecretFile: secretFile,
+		Scopes:           []string{"metrics"},
+	}}, sharedHTTPClient)
+	if err != nil {
+		t.Fatalf("Failed to create authenticator: %v", err)
+	}
+	client := &Client{URL: server.URL, Auth: auth, HTTPClient: sharedHTTPClient}
+	for i := 0; i < 3; i++ {
+		if _, err := client.Query(context.Background(), "up"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&tokenRequests); n != 1 {
+		t.Errorf("Expected the token to be cached, got %d token requests", n)
+	}
+
+	auth, _ = NewAuthenticator(AuthConfig{Type: AuthOAuth2, OAuth2: &OAuth2Config{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "clustercheck",
+		ClientSecret: "wrong",
+	}}, sharedHTTPClient)
+	client.Auth = auth
+	if _, err := client.Query(context.Background(), "up"); err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("Expected token error for wrong client secret, got: %v", err)
+	}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AuthConfig
+		want    string
+		errText string
+	}{
+		{"default basic", AuthConfig{Username: "user"}, "Basic (username: user)", ""},
+		{"none", AuthConfig{Type: AuthNone}, "None", ""},
+		{"bearer", AuthConfig{Type: AuthBearer, Token: "token"}, "Bearer (static token)", ""},
+		{"bearer file", AuthConfig{Type: AuthBearer, TokenFile: "/token"}, "Bearer (token file: /token)", ""},
+		{"oauth2", AuthConfig{Type: AuthOAuth2, OAuth2: &OAuth2Config{TokenURL: "https://sso/token", ClientID: "id"}}, "OAuth2 client credentials (client: id)", ""},
+		{"bearer token and file", AuthConfig{Type: AuthBearer, Token: "token", TokenFile: "/token"}, "", "either a token or tokenFile"},
+		{"oauth2 without config", AuthConfig{Type: AuthOAuth2}, "", "requires tokenURL and clientID"},
+		{"missing client secret file", AuthConfig{Type: AuthOAuth2, OAuth2: &OAuth2Config{TokenURL: "https://sso/token", ClientID: "id", ClientSecretFile: "/nonexistent/secret"}}, "", "failed to read client secret"},
+		{"unknown type", AuthConfig{Type: "digest"}, "", "unknown auth type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth, err := NewAuthenticator(tt.config, sharedHTTPClient)
+			if tt.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Errorf("Expected error containing %q, got: %v", tt.errText, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if auth.String() != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, auth.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package monitoringcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+)
+
+// sharedHTTPClient is used by clients created with NewClient. It verifies the
+// server certificate against the system roots.
+var sharedHTTPClient = &http.Client{Transport: newTransport(&tls.Config{})}
+
+// NewHTTPClient creates a keep-alive HTTP client for Prometheus. Timeouts are
+// set per query by the request context.
+func NewHTTPClient(opts checker.TLSOptions) (*http.Client, error) {
+	tlsConfig, err := NewTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: newTransport(tlsConfig)}, nil
+}
+
+// NewTLSConfig returns the TLS configuration for the given options. Server
+// certificates are verified against the system roots and the CA bundle.
+func NewTLSConfig(opts checker.TLSOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         opts.ServerName,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		if opts.CertFile == "" || opts.KeyFile == "" {
+			return nil, fmt.Errorf("client certificate and key are both required")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// newTransport returns a keep-alive transport respecting the proxy environment
+func newTransport(tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+// StatusError is returned for Prometheus responses with a server error or
+// throttling status code
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+// Error returns the status code and message of the response
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Prometheus returned %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Client queries the Prometheus HTTP API. It is safe for concurrent use.
+type Client struct {
+	URL        string
+	Auth       Authenticator
+	Headers    http.Header
+	Debug      bool
+	HTTPClient *http.Client
+}
+
+// NewClient creates a Prometheus API client with basic auth using the shared
+// keep-alive HTTP client
+func NewClient(prometheus string, username string, password string, debug bool) *Client {
+	return &Client{
+		URL:        prometheus,
+		Auth:       &basicAuth{username: username, password: password},
+		Debug:      debug,
+		HTTPClient: sharedHTTPClient,
+	}
+}
+
+// Name returns the URL of the endpoint without credentials
+func (c *Client) Name() string {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return c.URL
+	}
+	return u.Redacted()
+}
+
+// Query runs an instant query and returns all samples of the result
+func (c *Client) Query(ctx context.Context, query string) ([]Sample, error) {
+	params := url.Values{}
+	params.Add("query", query)
+	return c.get(ctx, "query", params)
+}
+
+// QueryRange runs a range query between start and end and returns one sample
+// per series with all values of the series
+func (c *Client) QueryRange(ctx context.Context, query string, start time.Time, end time.Time, step time.Duration) ([]Sample, error) {
+	params := url.Values{}
+	params.Add("query", query)
+	params.Add("start", strconv.FormatInt(start.Unix(), 10))
+	params.Add("end", strconv.FormatInt(end.Unix(), 10))
+	params.Add("step", strconv.FormatFloat(step.Seconds(), 'f', -1, 64))
+	return c.get(ctx, "query_range", params)
+}
+
+// get calls the given Prometheus query endpoint and parses the result
+func (c *Client) get(ctx context.Context, endpoint string, params url.Values) ([]Sample, error) {
+	url := fmt.Sprintf("%s/api/v1/%s?%s", c.URL, endpoint, params.Encode())
+
+	if c.Debug {
+		fmt.Fprintf(os.Stderr, "\n[DEBUG] Prometheus API Request:\n")
+		fmt.Fprintf(os.Stderr, "  URL: %s\n", url)
+		fmt.Fprintf(os.Stderr, "  Query: %s\n", params.Get("query"))
+		if c.Auth != nil {
+			fmt.Fprintf(os.Stderr, "  Auth: %s\n", c.Auth)
+		}
+		if tenant := c.Headers.Get(TenantHeader); tenant != "" {
+			fmt.Fprintf(os.Stderr, "  Tenant: %s\n", tenant)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.Auth != nil {
+		if err := c.Auth.Authenticate(req); err != nil {
+			return nil, err
+		}
+	}
+	for name, values := range c.Headers {
+		req.Header[name] = values
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		if c.Debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Prometheus API Error: %v\n\n", err)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if c.Debug {
+		fmt.Fprintf(os.Stderr, "  Status Code: %d\n", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Debug {
+		fmt.Fprintf(os.Stderr, "[DEBUG] Prometheus API Response:\n")
+		fmt.Fprintf(os.Stderr, "  Body: %s\n\n", string(body))
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		message := strings.TrimSpace(string(body))
+		var response queryResponse
+		if json.Unmarshal(body, &response) == nil && response.Error != "" {
+			message = response.Error
+		}
+		return nil, &StatusError{StatusCode: resp.StatusCode, Message: message}
+	}
+
+	return parseResponse(body)
+}
+
+// retryable reports whether a query failed with a transient error: a network
+// error or a server error or throttling response
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return true
+	}
+	return checker.IsNetworkError(err)
+}
@@ -0,0 +1,245 @@
+package monitoringcheck
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+)
+
+// testTLS trusts the certificate of the httptest TLS servers
+var testTLS checker.TLSOptions
+
+// TestMain makes the shared client and testTLS trust the certificate of the
+// httptest TLS servers, so the tests run with TLS verification enabled
+func TestMain(m *testing.M) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	cert := server.Certificate()
+	server.Close()
+
+	dir, err := os.MkdirTemp("", "clustercheck")
+	if err != nil {
+		panic(err)
+	}
+	testTLS.CAFile = filepath.Join(dir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	if err := os.WriteFile(testTLS.CAFile, caPEM, 0600); err != nil {
+		panic(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	sharedHTTPClient = &http.Client{Transport: newTransport(&tls.Config{RootCAs: roots})}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestClientKeepAlive(t *testing.T) {
+	var connections int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"1"]}]}}`))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	client := NewClient(server.URL, "user", "pass", false)
+	for i := 0; i < 5; i++ {
+		if _, err := client.Query(context.Background(), "up"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if n := atomic.LoadInt32(&connections); n != 1 {
+		t.Errorf("Expected the connection to be reused, got %d connections", n)
+	}
+}
+
+func TestClientBasicAuth(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "user" || pass != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"status":"error","error":"unauthorized"}`))
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+	}))
+	defer server.Close()
+
+	if _, err := NewClient(server.URL, "user", "pass", false).Query(context.Background(), "up"); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := NewClient(server.URL, "user", "wrong", false).Query(context.Background(), "up"); err == nil {
+		t.Error("Expected error for wrong credentials, got nil")
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	invalidCA := filepath.Join(dir, "invalid.pem")
+	os.WriteFile(invalidCA, []byte("not a certificate"), 0600)
+	certFile, keyFile := writeClientCert(t)
+
+	tests := []struct {
+		name    string
+		opts    checker.TLSOptions
+		errText string
+	}{
+		{"defaults", checker.TLSOptions{}, ""},
+		{"CA bundle", checker.TLSOptions{CAFile: testTLS.CAFile}, ""},
+		{"client certificate", checker.TLSOptions{CertFile: certFile, KeyFile: keyFile}, ""},
+		{"missing CA bundle", checker.TLSOptions{CAFile: filepath.Join(dir, "missing.pem")}, "failed to read CA bundle"},
+		{"invalid CA bundle", checker.TLSOptions{CAFile: invalidCA}, "no certificates found"},
+		{"missing key", checker.TLSOptions{CertFile: certFile}, "both required"},
+		{"invalid client certificate", checker.TLSOptions{CertFile: invalidCA, KeyFile: keyFile}, "failed to load client certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTLSConfig(tt.opts)
+			if tt.errText == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errText) {
+				t.Errorf("Expected error containing %q, got: %v", tt.errText, err)
+			}
+		})
+	}
+}
+
+func TestClientTLSVerification(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+	}))
+	defer server.Close()
+
+	// the httptest certificate is valid for example.com and 127.0.0.1
+	tests := []struct {
+		name    string
+		opts    checker.TLSOptions
+		wantErr bool
+	}{
+		{"unknown authority", checker.TLSOptions{}, true},
+		{"CA bundle", checker.TLSOptions{CAFile: testTLS.CAFile}, false},
+		{"server name", checker.TLSOptions{CAFile: testTLS.CAFile, ServerName: "example.com"}, false},
+		{"wrong server name", checker.TLSOptions{CAFile: testTLS.CAFile, ServerName: "prometheus.example.org"}, true},
+		{"insecure", checker.TLSOptions{InsecureSkipVerify: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			httpClient, err := NewHTTPClient(tt.opts)
+			if err != nil {
+				t.Fatalf("Failed to create HTTP client: %v", err)
+			}
+			client := NewClient(server.URL, "", "", false)
+			client.HTTPClient = httpClient
+
+			_, err = client.Query(context.Background(), "up")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Expected certificate verification error, got nil")
+				}
+				if checker.IsNetworkError(err) {
+					t.Errorf("Expected certificate error not to be retried: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestClientMutualTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	certFile, keyFile := writeClientCert(t)
+	for _, opts := range []checker.TLSOptions{
+		{CAFile: testTLS.CAFile},
+		{CAFile: testTLS.CAFile, CertFile: certFile, KeyFile: keyFile},
+	} {
+		httpClient, err := NewHTTPClient(opts)
+		if err != nil {
+			t.Fatalf("Failed to create HTTP client: %v", err)
+		}
+		client := NewClient(server.URL, "", "", false)
+		client.HTTPClient = httpClient
+
+		_, err = client.Query(context.Background(), "up")
+		if opts.CertFile == "" && err == nil {
+			t.Error("Expected error without client certificate, got nil")
+		}
+		if opts.CertFile != "" && err != nil {
+			t.Errorf("Unexpected error with client certificate: %v", err)
+		}
+	}
+}
+
+// writeClientCert writes a self-signed client certificate and key and returns their paths
+func writeClientCert(t *testing.T) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "clustercheck"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
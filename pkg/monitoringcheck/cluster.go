@@ -0,0 +1,311 @@
+package monitoringcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"github.com/eumel8/clustercheck/pkg/common"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/yaml"
+)
+
+// KubeconfigExtension is the name of the kubeconfig cluster extension holding
+// the cluster labels of a cluster
+const KubeconfigExtension = "clustercheck.eumel8.github.com"
+
+// Sources of the cluster label
+const (
+	SourceFlag       = "flag"
+	SourceEnv        = "CLUSTER environment variable"
+	SourceMapping    = "cluster map"
+	SourceKubeconfig = "kubeconfig extension"
+	SourcePrometheus = "Prometheus lookup"
+	SourceContext    = "kube context"
+)
+
+// DefaultClusterLookupQuery returns the API server targets of all clusters
+const DefaultClusterLookupQuery = `group by (cluster, instance) (up{job=~"apiserver|kube-apiserver"})`
+
+// ClusterLabels are the cluster label values of the queries, see QueryData
+type ClusterLabels struct {
+	Cluster      string `json:"cluster,omitempty"`
+	ShortCluster string `json:"shortCluster,omitempty"`
+	// Source describes where the cluster label was taken from
+	Source string `json:"-"`
+}
+
+// ClusterMapping maps kube context names and API server URLs to cluster labels
+type ClusterMapping struct {
+	Contexts map[string]ClusterLabels `json:"contexts,omitempty"`
+	Servers  map[string]ClusterLabels `json:"servers,omitempty"`
+}
+
+// ClusterLookup configures the lookup of the cluster label in Prometheus. The
+// label of the series whose address label matches the API server of the kube
+// context is used.
+type ClusterLookup struct {
+	// Query returns the API server series, defaults to DefaultClusterLookupQuery
+	Query string `json:"query,omitempty"`
+	// Label holds the cluster label value, defaults to "cluster"
+	Label string `json:"label,omitempty"`
+	// AddressLabel holds the API server host:port, defaults to "instance"
+	AddressLabel string `json:"addressLabel,omitempty"`
+}
+
+// LoadClusterMapping loads the cluster mapping file
+func LoadClusterMapping(path string) (*ClusterMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster map %s: %v", path, err)
+	}
+
+	mapping := &ClusterMapping{}
+	if err := yaml.UnmarshalStrict(data, mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster map %s: %v", path, err)
+	}
+	for name, labels := range mapping.Contexts {
+		if labels.Cluster == "" && labels.ShortCluster == "" {
+			return nil, fmt.Errorf("cluster map %s: context %s has no cluster or shortCluster", path, name)
+		}
+	}
+	for server, labels := range mapping.Servers {
+		if labels.Cluster == "" && labels.ShortCluster == "" {
+			return nil, fmt.Errorf("cluster map %s: server %s has no cluster or shortCluster", path, server)
+		}
+	}
+	return mapping, nil
+}
+
+// Lookup returns the labels of the kube context, or of the API server URL if
+// the context is not mapped
+func (m *ClusterMapping) Lookup(context string, server string) (ClusterLabels, bool) {
+	if labels, ok := m.Contexts[context]; ok && context != "" {
+		return labels, true
+	}
+	if server == "" {
+		return ClusterLabels{}, false
+	}
+	for mapped, labels := range m.Servers {
+		if strings.TrimSuffix(mapped, "/") == strings.TrimSuffix(server, "/") {
+			return labels, true
+		}
+	}
+	return ClusterLabels{}, false
+}
+
+// withDefaults returns the lookup with the default query and labels
+func (l ClusterLookup) withDefaults() ClusterLookup {
+	if l.Query == "" {
+		l.Query = DefaultClusterLookupQuery
+	}
+	if l.Label == "" {
+		l.Label = "cluster"
+	}
+	if l.AddressLabel == "" {
+		l.AddressLabel = "instance"
+	}
+	return l
+}
+
+// kubeCluster is the current kube context with the API server URL and the
+// cluster labels of the kubeconfig extension
+type kubeCluster struct {
+	Context string
+	Server  string
+	Labels  ClusterLabels
+}
+
+// currentKubeCluster reads the current context of the kubeconfig selected by
+// kube. The context is replaced by the cluster name of kube, if set.
+func currentKubeCluster(ctx context.Context, kube checker.KubeOptions) (kubeCluster, error) {
+	current := kubeCluster{}
+	if kube.ClusterName != "" || kube.ClusterNameConfigMap != "" {
+		name, err := common.ClusterName(ctx, kube)
+		if err != nil {
+			return current, err
+		}
+		current.Context = name
+	}
+
+	kubeContext, err := common.CurrentKubeContext(kube)
+	if err != nil {
+		// the cluster name is sufficient without kubeconfig
+		if current.Context != "" {
+			return current, nil
+		}
+		return current, err
+	}
+
+	if current.Context == "" {
+		current.Context = kubeContext.Name
+	}
+	if kubeContext.Cluster == nil {
+		return current, nil
+	}
+	current.Server = kubeContext.Cluster.Server
+	current.Labels, err = extensionLabels(kubeContext.Cluster)
+	if err != nil {
+		return current, fmt.Errorf("cluster %s: %v", kubeContext.ClusterName, err)
+	}
+	return current, nil
+}
+
+// extensionLabels returns the cluster labels of the kubeconfig extension of the cluster
+func extensionLabels(cluster *clientcmdapi.Cluster) (ClusterLabels, error) {
+	labels := ClusterLabels{}
+	extension, ok := cluster.Extensions[KubeconfigExtension]
+	if !ok {
+		return labels, nil
+	}
+	unknown, ok := extension.(*runtime.Unknown)
+	if !ok {
+		return labels, fmt.Errorf("invalid extension %s", KubeconfigExtension)
+	}
+	if err := json.Unmarshal(unknown.Raw, &labels); err != nil {
+		return labels, fmt.Errorf("invalid extension %s: %v", KubeconfigExtension, err)
+	}
+	return labels, nil
+}
+
+// resolveCluster returns the cluster labels of the queries and the current
+// kube context. The cluster label is taken from the first source which sets
+// it: the flag, CLUSTER, the cluster map, the kubeconfig extension, or the kube
+// context (or cluster name) with the FQDN. The short cluster label is resolved
+// the same way and defaults to the kube context.
+func (c *Checker) resolveCluster(ctx context.Context) (ClusterLabels, kubeCluster, error) {
+	current, err := currentKubeCluster(ctx, c.kube)
+	if err != nil && current.Context == "" && c.kube.ClusterNameConfigMap == "" {
+		// without kubeconfig the labels are set by the flags and CLUSTER only
+		current = kubeCluster{Context: "unknown"}
+	} else if err != nil {
+		return ClusterLabels{}, current, fmt.Errorf("failed to read kubeconfig: %v", err)
+	}
+
+	sources := []ClusterLabels{
+		{Cluster: c.clusterLabel, ShortCluster: c.shortClusterLabel, Source: SourceFlag},
+		{Cluster: os.Getenv("CLUSTER"), Source: SourceEnv},
+	}
+	if c.clusterMap != "" {
+		mapping, err := LoadClusterMapping(c.clusterMap)
+		if err != nil {
+			return ClusterLabels{}, current, err
+		}
+		if labels, ok := mapping.Lookup(current.Context, current.Server); ok {
+			labels.Source = SourceMapping
+			sources = append(sources, labels)
+		}
+	}
+	current.Labels.Source = SourceKubeconfig
+	sources = append(sources, current.Labels)
+
+	cluster := current.Context
+	if c.fqdn != "" {
+		cluster = cluster + "." + c.fqdn
+	}
+	if clcFQDN := os.Getenv("CLUSTERCHECK_FQDN"); clcFQDN != "" {
+		cluster = cluster + "." + clcFQDN
+	}
+	sources = append(sources, ClusterLabels{Cluster: cluster, ShortCluster: current.Context, Source: SourceContext})
+
+	labels := ClusterLabels{}
+	for _, source := range sources {
+		if labels.Cluster == "" && source.Cluster != "" {
+			labels.Cluster = source.Cluster
+			labels.Source = source.Source
+		}
+		if labels.ShortCluster == "" {
+			labels.ShortCluster = source.ShortCluster
+		}
+	}
+	return labels, current, nil
+}
+
+// lookupCluster queries the cluster label of the API server in Prometheus
+func (c *Checker) lookupCluster(ctx context.Context, pool *endpointPool, lookup ClusterLookup, server string) (string, error) {
+	if server == "" {
+		return "", fmt.Errorf("kube context has no API server")
+	}
+	lookup = lookup.withDefaults()
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	addresses, err := apiServerAddresses(ctx, server)
+	if err != nil {
+		return "", err
+	}
+
+	var samples []Sample
+	for _, client := range pool.order() {
+		samples, _, err = c.queryEndpoint(ctx, client, func(ctx context.Context, client *Client) ([]Sample, error) {
+			return client.Query(ctx, lookup.Query)
+		})
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to query API servers: %v", err)
+	}
+
+	found := map[string]bool{}
+	for _, sample := range samples {
+		if addresses[sample.Metric[lookup.AddressLabel]] && sample.Metric[lookup.Label] != "" {
+			found[sample.Metric[lookup.Label]] = true
+		}
+	}
+	clusters := []string{}
+	for cluster := range found {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+
+	switch len(clusters) {
+	case 0:
+		return "", fmt.Errorf("no %s series matches the API server %s", lookup.Label, server)
+	case 1:
+		return clusters[0], nil
+	default:
+		return "", fmt.Errorf("API server %s matches several clusters: %s", server, strings.Join(clusters, ", "))
+	}
+}
+
+// apiServerAddresses returns the host and the resolved IP addresses of the API
+// server URL, each with and without port
+func apiServerAddresses(ctx context.Context, server string) (map[string]bool, error) {
+	u, err := url.Parse(server)
+	if err != nil || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid API server URL %q", server)
+	}
+
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+
+	hosts := []string{u.Hostname()}
+	if net.ParseIP(u.Hostname()) == nil {
+		// the targets of the API server are usually scraped by IP address
+		if ips, err := net.DefaultResolver.LookupHost(ctx, u.Hostname()); err == nil {
+			hosts = append(hosts, ips...)
+		}
+	}
+
+	addresses := map[string]bool{}
+	for _, host := range hosts {
+		addresses[host] = true
+		addresses[net.JoinHostPort(host, port)] = true
+	}
+	return addresses, nil
+}
@@ -0,0 +1,222 @@
+package monitoringcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+)
+
+// writeKubeconfig writes a kubeconfig with the context prod of the API server
+// and the given cluster extension and sets KUBECONFIG
+func writeKubeconfig(t *testing.T, server string, extension string) {
+	t.Helper()
+	content := `apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: prod
+  cluster:
+    server: ` + server + "\n"
+	if extension != "" {
+		content += "    extensions:\n    - name: " + KubeconfigExtension + "\n      extension: " + extension + "\n"
+	}
+	content += `contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: prod
+users:
+- name: prod
+  user: {}
+`
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+	t.Setenv("KUBECONFIG", path)
+}
+
+func TestLoadClusterMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid", "contexts:\n  prod:\n    cluster: prod.example.com\nservers:\n  https://api.example.com:6443:\n    shortCluster: prod\n", ""},
+		{"context without labels", "contexts:\n  prod: {}\n", "context prod has no cluster or shortCluster"},
+		{"server without labels", "servers:\n  https://api.example.com: {}\n", "server https://api.example.com has no cluster or shortCluster"},
+		{"unknown field", "clusters:\n  prod: prod\n", "unknown field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadClusterMapping(writeConfig(t, tt.content))
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("LoadClusterMapping() returned error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := LoadClusterMapping(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for a missing cluster map")
+	}
+}
+
+func TestClusterMappingLookup(t *testing.T) {
+	mapping := &ClusterMapping{
+		Contexts: map[string]ClusterLabels{"admin@prod": {Cluster: "prod-context"}},
+		Servers:  map[string]ClusterLabels{"https://api.prod.example.com:6443/": {Cluster: "prod-server"}},
+	}
+
+	tests := []struct {
+		name    string
+		context string
+		server  string
+		want    string
+		found   bool
+	}{
+		{"context", "admin@prod", "https://api.prod.example.com:6443", "prod-context", true},
+		{"server", "other", "https://api.prod.example.com:6443", "prod-server", true},
+		{"not mapped", "other", "https://api.other.example.com", "", false},
+		{"no server", "other", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, found := mapping.Lookup(tt.context, tt.server)
+			if found != tt.found || labels.Cluster != tt.want {
+				t.Errorf("Lookup() = %+v, %v, want %s, %v", labels, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestResolveCluster(t *testing.T) {
+	mapping := writeConfig(t, "servers:\n  https://10.0.0.1:6443:\n    cluster: prod-map\n    shortCluster: prod-short\n")
+	extension := "{cluster: prod-extension}"
+
+	tests := []struct {
+		name      string
+		checker   Checker
+		env       string
+		extension string
+		want      ClusterLabels
+	}{
+		{"kube context", Checker{fqdn: "example.com"}, "", "", ClusterLabels{Cluster: "prod.example.com", ShortCluster: "prod", Source: SourceContext}},
+		{"kubeconfig extension", Checker{fqdn: "example.com"}, "", extension, ClusterLabels{Cluster: "prod-extension", ShortCluster: "prod", Source: SourceKubeconfig}},
+		{"cluster map", Checker{clusterMap: mapping}, "", extension, ClusterLabels{Cluster: "prod-map", ShortCluster: "prod-short", Source: SourceMapping}},
+		{"environment", Checker{clusterMap: mapping}, "prod-env", extension, ClusterLabels{Cluster: "prod-env", ShortCluster: "prod-short", Source: SourceEnv}},
+		{"flags", Checker{clusterMap: mapping, clusterLabel: "prod-flag", shortClusterLabel: "flag"}, "prod-env", "", ClusterLabels{Cluster: "prod-flag", ShortCluster: "flag", Source: SourceFlag}},
+		{"cluster name", Checker{fqdn: "example.com", kube: checker.KubeOptions{ClusterName: "prod-name"}}, "", "", ClusterLabels{Cluster: "prod-name.example.com", ShortCluster: "prod-name", Source: SourceContext}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeKubeconfig(t, "https://10.0.0.1:6443", tt.extension)
+			t.Setenv("CLUSTER", tt.env)
+			t.Setenv("CLUSTERCHECK_FQDN", "")
+
+			labels, current, err := tt.checker.resolveCluster(context.Background())
+			if err != nil {
+				t.Fatalf("resolveCluster() returned error: %v", err)
+			}
+			if labels != tt.want {
+				t.Errorf("resolveCluster() = %+v, want %+v", labels, tt.want)
+			}
+			if current.Server != "https://10.0.0.1:6443" {
+				t.Errorf("Expected the API server of the current context, got %+v", current)
+			}
+		})
+	}
+
+	t.Run("invalid extension", func(t *testing.T) {
+		writeKubeconfig(t, "https://10.0.0.1:6443", "[prod]")
+		if _, _, err := (&Checker{}).resolveCluster(context.Background()); err == nil || !strings.Contains(err.Error(), KubeconfigExtension) {
+			t.Errorf("Expected error for an invalid extension, got %v", err)
+		}
+	})
+
+	t.Run("no kubeconfig", func(t *testing.T) {
+		t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+		t.Setenv("CLUSTER", "")
+		labels, _, err := (&Checker{}).resolveCluster(context.Background())
+		if err != nil || labels.Cluster != "unknown" || labels.ShortCluster != "unknown" {
+			t.Errorf("Expected unknown cluster, got %+v, %v", labels, err)
+		}
+
+		labels, _, err = (&Checker{kube: checker.KubeOptions{ClusterName: "prod-name"}}).resolveCluster(context.Background())
+		if err != nil || labels.Cluster != "prod-name" {
+			t.Errorf("Expected the cluster name without kubeconfig, got %+v, %v", labels, err)
+		}
+	})
+}
+
+func TestAPIServerAddresses(t *testing.T) {
+	addresses, err := apiServerAddresses(context.Background(), "https://10.0.0.1:6443")
+	if err != nil {
+		t.Fatalf("apiServerAddresses() returned error: %v", err)
+	}
+	if !addresses["10.0.0.1:6443"] || !addresses["10.0.0.1"] || len(addresses) != 2 {
+		t.Errorf("Expected host with and without port, got %v", addresses)
+	}
+
+	addresses, _ = apiServerAddresses(context.Background(), "https://[fd00::1]")
+	if !addresses["[fd00::1]:443"] {
+		t.Errorf("Expected the default HTTPS port, got %v", addresses)
+	}
+
+	if _, err := apiServerAddresses(context.Background(), "api.example.com:6443"); err == nil {
+		t.Error("Expected error for an API server URL without scheme")
+	}
+}
+
+func TestCheckerRunClusterLookup(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("query") == DefaultClusterLookupQuery {
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[` +
+				`{"metric":{"cluster":"prod-prometheus","instance":"10.0.0.1:6443"},"value":[0,"1"]},` +
+				`{"metric":{"cluster":"dev","instance":"10.0.0.2:6443"},"value":[0,"1"]}]}}`))
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"1"]}]}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("PROMETHEUS_URL", server.URL)
+	t.Setenv("CLUSTER", "")
+	t.Setenv("CLUSTERCHECK_FQDN", "")
+	t.Setenv("CLUSTERCHECK_BW", "")
+	config := writeConfig(t, "replaceDefaults: true\nchecks:\n- name: UP\n  query: 'up{cluster=\"{{.Cluster}}\"}'\n")
+
+	tests := []struct {
+		name   string
+		server string
+		want   string
+		source string
+	}{
+		{"matching API server", "https://10.0.0.1:6443", "prod-prometheus", SourcePrometheus},
+		{"unknown API server", "https://10.0.0.3:6443", "prod", SourceContext},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeKubeconfig(t, tt.server, "")
+			results := NewChecker(checker.Options{PrometheusTLS: testTLS, ConfigFile: config, ClusterLookup: true}).Run(context.Background())
+			if len(results) != 1 || !results[0].Passed {
+				t.Fatalf("Expected UP to pass, got %+v", results)
+			}
+			if results[0].Target != tt.want || results[0].TargetSource != tt.source || results[0].Query != `up{cluster="`+tt.want+`"}` {
+				t.Errorf("Expected cluster label %s from %s, got %+v", tt.want, tt.source, results[0])
+			}
+		})
+	}
+}
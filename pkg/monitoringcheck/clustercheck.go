@@ -7,27 +7,18 @@ package monitoringcheck
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"net/url"
 	"os"
 	"os/exec"
-	"path/filepath"
-	"time"
+	"sort"
 	"strings"
-	"github.com/mattn/go-runewidth"
-
-	helmv2 "github.com/fluxcd/helm-controller/api/v2"
-	kustomizev1 "github.com/fluxcd/kustomize-controller/api/v1"
-	"k8s.io/apimachinery/pkg/runtime"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/kubernetes/scheme"
-	"k8s.io/client-go/tools/clientcmd"
-	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sync"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"github.com/eumel8/clustercheck/pkg/common"
 )
 
 // Struct to hold Bitwarden login fields
@@ -79,684 +70,531 @@ type PrometheusQueries struct {
 
 // GetCurrentContext returns the current kubernetes context
 func GetCurrentContext() (string, error) {
-	kubeconfigPath := filepath.Join(os.Getenv("HOME"), ".kube", "config")
-
-	config, err := clientcmd.LoadFromFile(kubeconfigPath)
-	if err != nil {
-		return "", err
-	}
-
-	return config.CurrentContext, nil
+	return common.GetCurrentContext()
 }
 
-// QueryPrometheus queries Prometheus with the given parameters
-func QueryPrometheus(prometheus string, query string, username string, password string, debug bool) (string, error) {
-	value := "0"
-	params := url.Values{}
-	params.Add("query", query)
-	url := fmt.Sprintf("%s/api/v1/query?%s", prometheus, params.Encode())
-
-	if debug {
-		fmt.Printf("\n[DEBUG] Prometheus API Request:\n")
-		fmt.Printf("  URL: %s\n", url)
-		fmt.Printf("  Query: %s\n", query)
-		if username != "" {
-			fmt.Printf("  Auth: Basic (username: %s)\n", username)
-		}
-	}
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return value, err
-	}
-	req.SetBasicAuth(username, password)
-
-	// skip TLS verification
-	insecureClient := &http.Client{
-		Timeout: time.Second * 10,
-		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		},
-	}
-	resp, err := insecureClient.Do(req)
-	if err != nil {
-		if debug {
-			fmt.Printf("[DEBUG] Prometheus API Error: %v\n\n", err)
-		}
-		return value, err
-	}
-	defer resp.Body.Close()
-
-	if debug {
-		fmt.Printf("  Status Code: %d\n", resp.StatusCode)
+// QueryPrometheus queries Prometheus with the given parameters and returns the
+// value of the first sample, or "0" if the result is empty
+func QueryPrometheus(ctx context.Context, prometheus string, query string, username string, password string, debug bool) (string, error) {
+	samples, err := QueryPrometheusSamples(ctx, prometheus, query, username, password, debug)
+	if err != nil || len(samples) == 0 {
+		return "0", err
 	}
+	return samples[0].Value, nil
+}
 
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return value, err
-	}
+// QueryPrometheusSamples runs an instant query and returns all samples of the result
+func QueryPrometheusSamples(ctx context.Context, prometheus string, query string, username string, password string, debug bool) ([]Sample, error) {
+	return NewClient(prometheus, username, password, debug).Query(ctx, query)
+}
 
-	if debug {
-		fmt.Printf("[DEBUG] Prometheus API Response:\n")
-		fmt.Printf("  Body: %s\n\n", string(body))
-	}
+// QueryPrometheusRange runs a range query between start and end and returns
+// one sample per series with all values of the series
+func QueryPrometheusRange(ctx context.Context, prometheus string, query string, start time.Time, end time.Time, step time.Duration, username string, password string, debug bool) ([]Sample, error) {
+	return NewClient(prometheus, username, password, debug).QueryRange(ctx, query, start, end, step)
+}
 
-	// Define a structure matching the Prometheus response
-	var result struct {
-		Status string `json:"status"`
-		Data   struct {
-			ResultType string `json:"resultType"`
-			Result     []struct {
-				Metric map[string]string `json:"metric"`
-				Value  [2]interface{}    `json:"value"`
-			} `json:"result"`
-		} `json:"data"`
-	}
+func init() {
+	checker.Register("prometheus", 30, NewChecker)
+}
 
-	// Parse JSON response
-	err = json.Unmarshal(body, &result)
-	if err != nil {
-		return value, err
-	}
+// Checker runs the Prometheus monitoring queries as a registered checker
+type Checker struct {
+	bitwarden   bool
+	fqdn        string
+	debug       bool
+	configFile  string
+	parallelism int
+	timeout     time.Duration
+	retry       checker.Retry
+	tls         checker.TLSOptions
+	// serviceProxy queries the service or the service discovered by the
+	// selector through the API server
+	serviceProxy bool
+	service      string
+	selector     string
+	// portForward queries a pod of the service through a temporary port-forward
+	portForward bool
+	// strategy of several endpoints, StrategyFailover or StrategyCompare
+	strategy string
+	// clusterLabel and shortClusterLabel override the cluster labels,
+	// clusterMap maps kube contexts to cluster labels and clusterLookup
+	// looks up the cluster label in Prometheus
+	clusterLabel      string
+	shortClusterLabel string
+	clusterMap        string
+	clusterLookup     bool
+	// kube selects the kubeconfig of the service proxy, port-forward and
+	// cluster label
+	kube checker.KubeOptions
+}
 
-	// Extract the value (second element in the Value array)
-	if len(result.Data.Result) > 0 {
-		value = result.Data.Result[0].Value[1].(string)
+// NewChecker creates a Prometheus monitoring Checker
+func NewChecker(opts checker.Options) checker.Checker {
+	parallelism := opts.Parallelism
+	if parallelism <= 0 {
+		parallelism = checker.DefaultParallelism
+	}
+	return &Checker{
+		bitwarden:    opts.Bitwarden,
+		fqdn:         opts.FQDN,
+		debug:        opts.Debug,
+		configFile:   opts.ConfigFile,
+		parallelism:  parallelism,
+		timeout:      opts.Timeout(),
+		retry:        opts.Retry(),
+		tls:          opts.PrometheusTLS,
+		serviceProxy: opts.ServiceProxy && !opts.PortForward,
+		service:      opts.PrometheusService,
+		selector:     opts.PrometheusSelector,
+		portForward:  opts.PortForward,
+		strategy:     opts.PrometheusStrategy,
+
+		clusterLabel:      opts.ClusterLabel,
+		shortClusterLabel: opts.ShortClusterLabel,
+		clusterMap:        opts.ClusterMap,
+		clusterLookup:     opts.ClusterLookup,
+		kube:              opts.Kube,
 	}
+}
 
-	return value, nil
+// Name returns the display name of the check
+func (c *Checker) Name() string {
+	return "Prometheus Monitoring"
 }
 
-// getKubeConfig returns the path to the kubeconfig file
-func getKubeConfig() string {
-	// Check if KUBECONFIG env var is set
-	if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" {
-		return kubeconfig
-	}
-	// Default to $HOME/.kube/config
-	return filepath.Join(os.Getenv("HOME"), ".kube", "config")
+// Category returns the check category
+func (c *Checker) Category() string {
+	return checker.CategoryPrometheus
 }
 
-// CheckPods checks if all pods in the cluster are in Running or Succeeded state
-func CheckPods(namespace string, debug bool) error {
-	kubeconfigPath := getKubeConfig()
+// Run executes the Prometheus queries concurrently and returns one result per
+// query in the order of the configuration
+func (c *Checker) Run(ctx context.Context) []checker.Result {
+	results := []checker.Result{}
 
-	// Build config from kubeconfig file
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	config, err := LoadConfig(c.configFile)
 	if err != nil {
-		return fmt.Errorf("failed to build config: %v", err)
+		return append(results, checker.Result{
+			Name:     "Prometheus Configuration",
+			Category: c.Category(),
+			Passed:   false,
+			Message:  err.Error(),
+		})
 	}
 
-	// Create clientset
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		return fmt.Errorf("failed to create clientset: %v", err)
-	}
+	var endpoints []Endpoint
+	var httpClient *http.Client
+	switch {
+	case c.serviceProxy:
+		proxyCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		proxyURL, proxyClient, err := ServiceProxy(proxyCtx, c.kube, c.service, c.selector, c.retry, c.debug)
+		timedOut := checker.IsTimeout(proxyCtx, err)
+		cancel()
+		if err != nil {
+			return append(results, checker.Result{
+				Name:     "Prometheus Service Proxy",
+				Category: c.Category(),
+				Passed:   false,
+				TimedOut: timedOut,
+				Message:  err.Error(),
+			})
+		}
+		// the API server authenticates the requests with the kubeconfig
+		// credentials, an Authorization header for Prometheus would replace them
+		endpoint := forwardedEndpoint(config, proxyURL)
+		endpoint.Auth = AuthConfig{Type: AuthNone}
+		endpoints = []Endpoint{endpoint}
+		httpClient = proxyClient
+
+	case c.portForward:
+		forwardCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		localURL, forwardClient, closeTunnel, err := PortForward(forwardCtx, c.kube, c.service, c.selector, c.tls, c.retry, c.debug)
+		timedOut := checker.IsTimeout(forwardCtx, err)
+		cancel()
+		if err != nil {
+			return append(results, checker.Result{
+				Name:     "Prometheus Port Forward",
+				Category: c.Category(),
+				Passed:   false,
+				TimedOut: timedOut,
+				Message:  err.Error(),
+			})
+		}
+		// the tunnel is closed when all queries are done
+		defer closeTunnel()
+		endpoints = []Endpoint{forwardedEndpoint(config, localURL)}
+		httpClient = forwardClient
 
-	// Get current context for display
-	currentContext, err := GetCurrentContext()
-	if err != nil {
-		currentContext = "unknown"
+	default:
+		endpoints = prometheusEndpoints(config)
 	}
 
-	fmt.Printf("\033[36mpodcheck \033[0m on %s\n", currentContext)
+	if httpClient == nil {
+		httpClient, err = NewHTTPClient(c.tls)
+		if err != nil {
+			return append(results, checker.Result{
+				Name:     "Prometheus TLS Configuration",
+				Category: c.Category(),
+				Passed:   false,
+				Message:  err.Error(),
+			})
+		}
+	}
 
-	// List pods
-	ctx := context.Background()
-	listOptions := metav1.ListOptions{}
-	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, listOptions)
+	labels, current, err := c.resolveCluster(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to list pods: %v", err)
+		return append(results, checker.Result{
+			Name:     "Prometheus Cluster Label",
+			Category: c.Category(),
+			Passed:   false,
+			Message:  err.Error(),
+		})
 	}
 
-	totalPods := len(pods.Items)
-	runningOrSucceeded := 0
-	failedPods := []string{}
+	clients := []*Client{}
+	var username, password string
+	credentialsRead := false
+	for _, endpoint := range endpoints {
+		auth := endpoint.Auth
+		// the basic auth credentials of the environment are read once for all endpoints
+		if auth.needsCredentials() {
+			if !credentialsRead {
+				username, password, err = credentials(c.bitwarden)
+				if err != nil {
+					return append(results, checker.Result{
+						Name:     "Prometheus Authentication",
+						Category: c.Category(),
+						Passed:   false,
+						Message:  err.Error(),
+					})
+				}
+				credentialsRead = true
+			}
+			auth.Username = username
+			auth.Password = password
+		}
 
-	for _, pod := range pods.Items {
-		phase := string(pod.Status.Phase)
-		podName := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+		headers, err := endpoint.RenderHeaders(labels.Cluster, labels.ShortCluster)
+		if err != nil {
+			return append(results, checker.Result{
+				Name:     "Prometheus Configuration",
+				Category: c.Category(),
+				Passed:   false,
+				Message:  fmt.Sprintf("endpoint %s: %v", endpoint.URL, err),
+			})
+		}
 
-		if phase == "Running" || phase == "Succeeded" {
-			runningOrSucceeded++
-			fmt.Printf("%s \033[32m🟢 %s\033[0m\n", podName, phase)
-		} else {
-			failedPods = append(failedPods, fmt.Sprintf("%s (%s)", podName, phase))
-			fmt.Printf("%s \033[31m🔴 %s\033[0m\n", podName, phase)
+		authenticator, err := NewAuthenticator(auth, httpClient)
+		if err != nil {
+			return append(results, checker.Result{
+				Name:     "Prometheus Authentication",
+				Category: c.Category(),
+				Passed:   false,
+				Message:  fmt.Sprintf("endpoint %s: %v", endpoint.URL, err),
+			})
 		}
+		clients = append(clients, &Client{URL: endpoint.URL, Auth: authenticator, Headers: headers, Debug: c.debug, HTTPClient: httpClient})
 	}
+	pool := newEndpointPool(clients)
 
-	fmt.Printf("\nSummary: %d/%d pods in Running or Succeeded state\n", runningOrSucceeded, totalPods)
-
-	if len(failedPods) > 0 {
-		fmt.Printf("\033[31mFailed pods:\033[0m\n")
-		for _, pod := range failedPods {
-			fmt.Printf("  - %s\n", pod)
+	// the lookup replaces the cluster label of the kube context only
+	if c.clusterLookup && labels.Source == SourceContext {
+		cluster, err := c.lookupCluster(ctx, pool, config.ClusterLookup, current.Server)
+		if err != nil && c.debug {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Cluster label lookup failed: %v\n", err)
+		}
+		if err == nil {
+			labels.Cluster = cluster
+			labels.Source = SourcePrometheus
+			// tenant and headers are rendered again with the cluster label found
+			for i, client := range clients {
+				client.Headers, err = endpoints[i].RenderHeaders(labels.Cluster, labels.ShortCluster)
+				if err != nil {
+					return append(results, checker.Result{
+						Name:     "Prometheus Configuration",
+						Category: c.Category(),
+						Passed:   false,
+						Message:  fmt.Sprintf("endpoint %s: %v", endpoints[i].URL, err),
+					})
+				}
+			}
 		}
-		return fmt.Errorf("%d pods not in Running or Succeeded state", len(failedPods))
 	}
 
-	return nil
-}
-
-// CheckFlux checks if all Flux HelmReleases and Kustomizations are in Ready state
-func CheckFlux(namespace string, debug bool) error {
-	kubeconfigPath := getKubeConfig()
-
-	// Build config from kubeconfig file
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
-	if err != nil {
-		return fmt.Errorf("failed to build config: %v", err)
+	if c.debug {
+		fmt.Fprintf(os.Stderr, "[DEBUG] Cluster label: %s (%s), short cluster label: %s\n", labels.Cluster, labels.Source, labels.ShortCluster)
 	}
 
-	// Get current context for display
-	currentContext, err := GetCurrentContext()
-	if err != nil {
-		currentContext = "unknown"
+	results = make([]checker.Result, len(config.Checks))
+	workers := make(chan struct{}, c.parallelism)
+	var wg sync.WaitGroup
+	for i, check := range config.Checks {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			results[i] = c.runQuery(ctx, pool, check, labels)
+		}()
 	}
+	wg.Wait()
 
-	fmt.Printf("\033[36mfluxcheck \033[0m on %s\n", currentContext)
+	return results
+}
 
-	// Create a new scheme and add Flux types
-	fluxScheme := runtime.NewScheme()
-	_ = scheme.AddToScheme(fluxScheme)
-	_ = helmv2.AddToScheme(fluxScheme)
-	_ = kustomizev1.AddToScheme(fluxScheme)
+// runQuery executes the query of a check against the endpoints of the pool and
+// compares the value with the expected one
+func (c *Checker) runQuery(ctx context.Context, pool *endpointPool, check QueryCheck, labels ClusterLabels) checker.Result {
+	result := checker.Result{
+		Name:         check.Name,
+		Category:     c.Category(),
+		Severity:     check.Severity,
+		Target:       labels.Cluster,
+		TargetSource: labels.Source,
+		StartedAt:    time.Now(),
+	}
+	if result.Severity == "" {
+		result.Severity = checker.SeverityCritical
+	}
 
-	// Create controller-runtime client
-	k8sClient, err := client.New(config, client.Options{Scheme: fluxScheme})
+	query, err := check.Render(labels.Cluster, labels.ShortCluster)
 	if err != nil {
-		return fmt.Errorf("failed to create client: %v", err)
+		result.Message = fmt.Sprintf("Query template error: %v", err)
+		return result
 	}
+	result.Query = query
 
-	ctx := context.Background()
-	totalResources := 0
-	readyResources := 0
-	failedResources := []string{}
-
-	// Check HelmReleases
-	helmReleaseList := &helmv2.HelmReleaseList{}
-	listOpts := []client.ListOption{}
-	if namespace != "" {
-		listOpts = append(listOpts, client.InNamespace(namespace))
+	expect, err := check.Expectation()
+	if err != nil {
+		result.Message = err.Error()
+		return result
 	}
 
-	err = k8sClient.List(ctx, helmReleaseList, listOpts...)
+	queryRange, step, err := check.RangeStep()
 	if err != nil {
-		return fmt.Errorf("failed to list HelmReleases: %v", err)
-	}
-
-	fmt.Printf("\n\033[1mHelmReleases:\033[0m\n")
-	for _, hr := range helmReleaseList.Items {
-		totalResources++
-		resourceName := fmt.Sprintf("%s/%s", hr.Namespace, hr.Name)
-		ready := false
-
-		// Check Ready condition
-		for _, condition := range hr.Status.Conditions {
-			if condition.Type == "Ready" {
-				if condition.Status == metav1.ConditionTrue {
-					ready = true
-					readyResources++
-					fmt.Printf("%s \033[32m🟢 Ready\033[0m (revision: %s)\n", resourceName, hr.Status.LastAttemptedRevision)
-				} else {
-					failedResources = append(failedResources, fmt.Sprintf("HelmRelease %s: %s", resourceName, condition.Message))
-					fmt.Printf("%s \033[31m🔴 Not Ready\033[0m - %s\n", resourceName, condition.Message)
-				}
-				break
-			}
-		}
-
-		if !ready && len(hr.Status.Conditions) == 0 {
-			failedResources = append(failedResources, fmt.Sprintf("HelmRelease %s: No conditions set", resourceName))
-			fmt.Printf("%s \033[33m⚠️  Unknown\033[0m - No conditions set\n", resourceName)
-		}
+		result.Message = err.Error()
+		return result
 	}
 
-	// Check Kustomizations
-	kustomizationList := &kustomizev1.KustomizationList{}
-	err = k8sClient.List(ctx, kustomizationList, listOpts...)
+	timeout, err := check.QueryTimeout(c.timeout)
 	if err != nil {
-		return fmt.Errorf("failed to list Kustomizations: %v", err)
-	}
-
-	fmt.Printf("\n\033[1mKustomizations:\033[0m\n")
-	for _, ks := range kustomizationList.Items {
-		totalResources++
-		resourceName := fmt.Sprintf("%s/%s", ks.Namespace, ks.Name)
-		ready := false
-
-		// Check Ready condition
-		for _, condition := range ks.Status.Conditions {
-			if condition.Type == "Ready" {
-				if condition.Status == metav1.ConditionTrue {
-					ready = true
-					readyResources++
-					fmt.Printf("%s \033[32m🟢 Ready\033[0m (revision: %s)\n", resourceName, ks.Status.LastAppliedRevision)
-				} else {
-					failedResources = append(failedResources, fmt.Sprintf("Kustomization %s: %s", resourceName, condition.Message))
-					fmt.Printf("%s \033[31m🔴 Not Ready\033[0m - %s\n", resourceName, condition.Message)
-				}
-				break
-			}
-		}
-
-		if !ready && len(ks.Status.Conditions) == 0 {
-			failedResources = append(failedResources, fmt.Sprintf("Kustomization %s: No conditions set", resourceName))
-			fmt.Printf("%s \033[33m⚠️  Unknown\033[0m - No conditions set\n", resourceName)
-		}
+		result.Message = err.Error()
+		return result
 	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	fmt.Printf("\n\033[1mSummary:\033[0m %d/%d resources Ready\n", readyResources, totalResources)
-
-	if len(failedResources) > 0 {
-		fmt.Printf("\033[31m\nFailed resources:\033[0m\n")
-		for _, resource := range failedResources {
-			fmt.Printf("  - %s\n", resource)
+	run := func(ctx context.Context, client *Client) ([]Sample, error) {
+		if queryRange > 0 {
+			end := time.Now()
+			return client.QueryRange(ctx, query, end.Add(-queryRange), end, step)
 		}
-		return fmt.Errorf("%d resources not Ready", len(failedResources))
+		return client.Query(ctx, query)
 	}
 
-	if totalResources == 0 {
-		fmt.Printf("\033[33mNo Flux resources found\033[0m\n")
+	if c.strategy == StrategyCompare && len(pool.clients) > 1 {
+		return c.compare(ctx, pool, result, expect, run)
 	}
-
-	return nil
-}
-
-// CheckResult represents the result of a health check
-type CheckResult struct {
-	Name    string
-	Passed  bool
-	Message string
-}
-
-// GateCheckResult represents the overall gate check result
-type GateCheckResult struct {
-	TotalChecks   int
-	PassedChecks  int
-	FailedChecks  int
-	HealthScore   float64
-	CheckResults  []CheckResult
-	OverallPassed bool
+	return c.failover(ctx, pool, result, expect, run)
 }
 
-func PrintBox(text string) {
-	textWidth := runewidth.StringWidth(text)
-	width := textWidth + 2
-
-	top := "╔" + strings.Repeat("═", width) + "╗"
-	bot := "╚" + strings.Repeat("═", width) + "╝"
-	mid := fmt.Sprintf("║ %s%s ║", text, strings.Repeat(" ", width-textWidth-1))
-
-	fmt.Println(top)
-	fmt.Println(mid)
-	fmt.Println(bot)
-
+// queryFunc runs the query of a check against the endpoint of client
+type queryFunc func(ctx context.Context, client *Client) ([]Sample, error)
+
+// queryEndpoint runs a query against an endpoint and retries transient errors.
+// It returns the samples and the number of attempts.
+func (c *Checker) queryEndpoint(ctx context.Context, client *Client, query queryFunc) ([]Sample, int, error) {
+	var samples []Sample
+	attempts, err := c.retry.Do(ctx, retryable, func() error {
+		var err error
+		samples, err = query(ctx, client)
+		return err
+	})
+	return samples, attempts, err
 }
 
-
-// GateCheck performs all health checks and computes an overall health score
-func GateCheck(namespace string, bitwarden bool, fqdn string, debug bool) (*GateCheckResult, error) {
-	result := &GateCheckResult{
-		CheckResults: []CheckResult{},
+// queryError describes a query which failed or timed out in the result
+func queryError(ctx context.Context, result checker.Result, err error) checker.Result {
+	if checker.IsTimeout(ctx, err) {
+		result.TimedOut = true
+		result.Message = fmt.Sprintf("Query timed out after %s", result.Duration.Round(time.Millisecond))
+		return result
 	}
-
-	// Get current context for display
-	currentContext, err := GetCurrentContext()
-	if err != nil {
-		currentContext = "unknown"
+	result.Message = fmt.Sprintf("Query error: %v", err)
+	if result.Attempts > 1 {
+		result.Message = fmt.Sprintf("Query error after %d attempts: %v", result.Attempts, err)
 	}
-        title := fmt.Sprintf("CLUSTER GATE CHECK - %s", currentContext)
-
-	PrintBox(title)
+	return result
+}
 
-	// 1. Pod Health Check
-	fmt.Printf("\033[1m[1/3] Pod Health Check\033[0m\n")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	podErr := CheckPods(namespace, debug)
-	if podErr == nil {
-		result.CheckResults = append(result.CheckResults, CheckResult{
-			Name:    "Pod Health",
-			Passed:  true,
-			Message: "All pods are in Running or Succeeded state",
-		})
-		result.PassedChecks++
-	} else {
-		result.CheckResults = append(result.CheckResults, CheckResult{
-			Name:    "Pod Health",
-			Passed:  false,
-			Message: podErr.Error(),
-		})
-		result.FailedChecks++
-	}
-	result.TotalChecks++
-	fmt.Println()
-
-	// 2. Flux Resources Check
-	fmt.Printf("\033[1m[2/3] Flux Resources Check\033[0m\n")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	fluxErr := CheckFlux(namespace, debug)
-	if fluxErr == nil {
-		result.CheckResults = append(result.CheckResults, CheckResult{
-			Name:    "Flux Resources",
-			Passed:  true,
-			Message: "All HelmReleases and Kustomizations are Ready",
-		})
-		result.PassedChecks++
+// evaluate compares every sample of a query result with the expectation and
+// reports the series as findings
+func evaluate(result checker.Result, expect Expectation, samples []Sample) checker.Result {
+	if !expect.Numeric() {
+		result.Value = fmt.Sprintf("%d series", len(samples))
+		result.Passed = expect.MatchCount(len(samples))
+		// series of a result expected to be empty are unhealthy, e.g. firing alerts
+		for _, sample := range samples {
+			finding := seriesFinding(sample)
+			finding.Healthy = expect.Op != OpEmpty
+			result.Findings = append(result.Findings, finding)
+		}
+	} else if len(samples) == 0 {
+		result.Message = fmt.Sprintf("Empty result (expected: %s)", expect)
+		return result
 	} else {
-		result.CheckResults = append(result.CheckResults, CheckResult{
-			Name:    "Flux Resources",
-			Passed:  false,
-			Message: fluxErr.Error(),
-		})
-		result.FailedChecks++
-	}
-	result.TotalChecks++
-	fmt.Println()
-
-	// 3. Prometheus Monitoring Check
-	fmt.Printf("\033[1m[3/3] Prometheus Monitoring Check\033[0m\n")
-	fmt.Println("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	monitoringChecks, monitoringPassed := runPrometheusChecks(bitwarden, fqdn, debug)
+		failed := []checker.Finding{}
+		var invalid error
+		for _, sample := range samples {
+			finding, err := evaluateSeries(expect, sample)
+			if err != nil {
+				invalid = err
+			}
+			if !finding.Healthy {
+				failed = append(failed, finding)
+			}
+			if len(sample.Metric) > 0 {
+				result.Findings = append(result.Findings, finding)
+			}
+		}
 
-	for _, check := range monitoringChecks {
-		result.CheckResults = append(result.CheckResults, check)
-		result.TotalChecks++
-		if check.Passed {
-			result.PassedChecks++
+		result.Passed = len(failed) == 0
+		if result.Passed {
+			result.Value = samples[0].Value
 		} else {
-			result.FailedChecks++
+			// show the value of the first failing series
+			result.Value = failed[0].Status
 		}
-	}
-
-	if monitoringPassed {
-		fmt.Printf("\n\033[32m✓ All Prometheus checks passed\033[0m\n")
-	} else {
-		fmt.Printf("\n\033[31m✗ Some Prometheus checks failed\033[0m\n")
-	}
-	fmt.Println()
 
-	// Calculate health score
-	if result.TotalChecks > 0 {
-		result.HealthScore = (float64(result.PassedChecks) / float64(result.TotalChecks)) * 100
-	}
-	result.OverallPassed = result.HealthScore >= 80.0
-
-	// Print Summary
-	fmt.Printf("\033[36m╔══════════════════════════════════════════════════╗\033[0m\n")
-	fmt.Printf("\033[36m║              GATE CHECK SUMMARY                  ║\033[0m\n")
-	fmt.Printf("\033[36m╚══════════════════════════════════════════════════╝\033[0m\n\n")
-
-	if result.OverallPassed {
-		fmt.Printf("\033[1;32m✓ CLUSTER HEALTH: PASSED\033[0m\n")
-	} else {
-		fmt.Printf("\033[1;31m✗ CLUSTER HEALTH: FAILED\033[0m\n")
-	}
-
-	fmt.Printf("\n\033[1mHealth Score: %.1f%% (%d of %d checks passed)\033[0m\n\n",
-		result.HealthScore, result.PassedChecks, result.TotalChecks)
-
-	// Detailed Results
-	fmt.Println("Detailed Results:")
-	fmt.Println("─────────────────────────────────────────────────")
-	for _, check := range result.CheckResults {
-		if check.Passed {
-			fmt.Printf("✓ \033[32m%-30s\033[0m PASS\n", check.Name)
-		} else {
-			fmt.Printf("✗ \033[31m%-30s\033[0m FAIL - %s\n", check.Name, check.Message)
+		if len(failed) > 0 && len(samples) > 1 {
+			result.Message = fmt.Sprintf("%d of %d series failed (expected: %s)", len(failed), len(samples), expect)
+			return result
+		}
+		if invalid != nil {
+			result.Message = fmt.Sprintf("Invalid value: %v", invalid)
+			return result
+		}
+		if len(failed) > 0 && failed[0].Message != "" {
+			result.Message = fmt.Sprintf("Value: %s, %s (expected: %s)", result.Value, failed[0].Message, expect)
+			return result
 		}
 	}
-	fmt.Println()
-
-	// Quality Gate Decision
-	fmt.Println("Quality Gate Decision:")
-	fmt.Println("─────────────────────────────────────────────────")
-	if result.HealthScore >= 90 {
-		fmt.Printf("\033[1;32m🟢 EXCELLENT - Ready for production\033[0m\n")
-	} else if result.HealthScore >= 80 {
-		fmt.Printf("\033[1;32m🟡 GOOD - Acceptable for go-live\033[0m\n")
-	} else if result.HealthScore >= 60 {
-		fmt.Printf("\033[1;33m🟠 FAIR - Review failures before go-live\033[0m\n")
-	} else {
-		fmt.Printf("\033[1;31m🔴 POOR - Not ready for production\033[0m\n")
-	}
-	fmt.Println()
 
-	if !result.OverallPassed {
-		return result, fmt.Errorf("cluster health check failed with score %.1f%%", result.HealthScore)
+	if result.Passed {
+		result.Message = "Healthy"
+	} else {
+		result.Message = fmt.Sprintf("Value: %s (expected: %s)", result.Value, expect)
 	}
-
-	return result, nil
+	return result
 }
 
-// runPrometheusChecks executes Prometheus monitoring checks and returns results
-func runPrometheusChecks(bitwarden bool, fqdn string, debug bool) ([]CheckResult, bool) {
-	results := []CheckResult{}
+// evaluateSeries compares every value of a series with the expectation. The
+// status of the finding is the first failing value.
+func evaluateSeries(expect Expectation, sample Sample) (checker.Finding, error) {
+	finding := seriesFinding(sample)
 
-	// static Prometheus API endpoint
-	prometheus := "https://127.0.0.1:9090"
-	username := os.Getenv("PROM_USER")
-	password := os.Getenv("PROM_PASS")
-	clcBW := os.Getenv("CLUSTERCHECK_BW")
-	clcFQDN := os.Getenv("CLUSTERCHECK_FQDN")
+	values := sample.Values
+	if values == nil {
+		values = []string{sample.Value}
+	}
 
-	if bitwarden == true || clcBW != "" {
-		itemName := "Prometheus Agent RemoteWrite"
-		jsonData, err := GetBitwardenItemJSON(itemName)
+	failed := 0
+	for _, v := range values {
+		value, err := ParseValue(v)
 		if err != nil {
-			results = append(results, CheckResult{
-				Name:    "Prometheus Authentication",
-				Passed:  false,
-				Message: fmt.Sprintf("Failed to get Bitwarden credentials: %v", err),
-			})
-			return results, false
+			finding.Status = v
+			finding.Message = err.Error()
+			return finding, err
 		}
-
-		var item BitwardenItem
-		err = json.Unmarshal(jsonData, &item)
-		if err != nil {
-			results = append(results, CheckResult{
-				Name:    "Prometheus Authentication",
-				Passed:  false,
-				Message: fmt.Sprintf("Failed to parse Bitwarden JSON: %v", err),
-			})
-			return results, false
+		if !expect.Match(value) {
+			if failed == 0 {
+				finding.Status = v
+			}
+			failed++
 		}
-
-		username = item.Login.Username
-		password = item.Login.Password
 	}
 
-	cluster, err := GetCurrentContext()
-	if err != nil {
-		cluster = "unknown"
+	finding.Healthy = failed == 0
+	if failed > 0 && len(values) > 1 {
+		finding.Message = fmt.Sprintf("%d of %d samples failed", failed, len(values))
 	}
+	return finding, nil
+}
 
-	if fqdn != "" {
-		cluster = cluster + "." + fqdn
+// seriesFinding returns the finding of a Prometheus series, named by its label set
+func seriesFinding(sample Sample) checker.Finding {
+	return checker.Finding{
+		Kind:   "Series",
+		Name:   seriesName(sample.Metric),
+		Status: sample.Value,
+		Labels: sample.Metric,
 	}
+}
 
-	if clcFQDN != "" {
-		cluster = cluster + "." + clcFQDN
+// seriesName formats a label set like the Prometheus UI, e.g. up{instance="a",job="b"}
+func seriesName(metric map[string]string) string {
+	keys := []string{}
+	for key := range metric {
+		if key != "__name__" {
+			keys = append(keys, key)
+		}
 	}
+	sort.Strings(keys)
 
-	if os.Getenv("PROMETHEUS_URL") != "" {
-		prometheus = os.Getenv("PROMETHEUS_URL")
+	labels := []string{}
+	for _, key := range keys {
+		labels = append(labels, fmt.Sprintf("%s=%q", key, metric[key]))
 	}
+	return metric["__name__"] + "{" + strings.Join(labels, ",") + "}"
+}
 
-	if os.Getenv("CLUSTER") != "" {
-		cluster = os.Getenv("CLUSTER")
+// forwardedEndpoint returns the endpoint of the service proxy or port-forward
+// URL with the authentication, tenant and headers of the first configured
+// endpoint, or of the environment without configured endpoints
+func forwardedEndpoint(config *Config, url string) Endpoint {
+	if len(config.Endpoints) > 0 {
+		endpoint := config.Endpoints[0]
+		endpoint.URL = url
+		return endpoint
 	}
+	return Endpoint{URL: url, Auth: envAuth(), Tenant: os.Getenv("PROM_TENANT")}
+}
 
-	queries := []PrometheusQueries{
-		{
-			Description: "API Server",
-			Query:       `avg(up{job="kube-apiserver",cluster="` + cluster + `"})`,
-		},
-		{
-			Description: "Kubelet",
-			Query:       `clamp((count(up{job="kubelet", cluster="` + cluster + `"}) > 3),1,1)`,
-		},
-		{
-			Description: "Node Status",
-			Query:       `min(kube_node_status_condition{condition="Ready",status="true",cluster="` + cluster + `"})`,
-		},
-		{
-			Description: "System Pods",
-			Query:       `clamp(sum(kube_pod_status_phase{namespace=~".*-system", phase!~"Running|Succeeded",cluster="` + cluster + `"} == 0),1,1)`,
-		},
+// envAuth returns the authentication of endpoints without configuration: a
+// bearer token from PROM_TOKEN_FILE or PROM_TOKEN, or basic auth
+func envAuth() AuthConfig {
+	if file := os.Getenv("PROM_TOKEN_FILE"); file != "" {
+		return AuthConfig{Type: AuthBearer, TokenFile: file}
 	}
-
-	allPassed := true
-	for _, query := range queries {
-		result, err := QueryPrometheus(prometheus, query.Query, username, password, debug)
-		if err != nil {
-			results = append(results, CheckResult{
-				Name:    query.Description,
-				Passed:  false,
-				Message: fmt.Sprintf("Query error: %v", err),
-			})
-			allPassed = false
-			fmt.Printf("  %s \033[31m✗ ERROR\033[0m - %v\n", query.Description, err)
-		} else {
-			if result == "1" {
-				results = append(results, CheckResult{
-					Name:    query.Description,
-					Passed:  true,
-					Message: "Healthy",
-				})
-				fmt.Printf("  %s \033[32m✓ OK\033[0m\n", query.Description)
-			} else {
-				results = append(results, CheckResult{
-					Name:    query.Description,
-					Passed:  false,
-					Message: fmt.Sprintf("Value: %s (expected: 1)", result),
-				})
-				allPassed = false
-				fmt.Printf("  %s \033[31m✗ FAIL\033[0m - Value: %s\n", query.Description, result)
-			}
-		}
+	if token := os.Getenv("PROM_TOKEN"); token != "" {
+		return AuthConfig{Type: AuthBearer, Token: token}
 	}
-
-	return results, allPassed
+	return AuthConfig{Type: AuthBasic}
 }
 
-// Run executes the cluster health check with the given options
-func Run(bitwarden bool, fqdn string, debug bool) {
-	// static Prometheus API endpoint
-	prometheus := "https://127.0.0.1:9090"
+// credentials returns the Prometheus basic auth credentials from the
+// environment or from Bitwarden
+func credentials(bitwarden bool) (string, string, error) {
 	username := os.Getenv("PROM_USER")
 	password := os.Getenv("PROM_PASS")
-	clcBW := os.Getenv("CLUSTERCHECK_BW")
-	clcFQDN := os.Getenv("CLUSTERCHECK_FQDN")
 
-	if bitwarden == true || clcBW != "" {
-		// doing bitwarden stuff here to get prometheus credentials
+	if bitwarden || os.Getenv("CLUSTERCHECK_BW") != "" {
 		itemName := "Prometheus Agent RemoteWrite"
 		jsonData, err := GetBitwardenItemJSON(itemName)
 		if err != nil {
-			fmt.Printf("Failed to get item from Bitwarden: %v\n", err)
+			return "", "", fmt.Errorf("Failed to get Bitwarden credentials: %v", err)
 		}
 
 		var item BitwardenItem
 		err = json.Unmarshal(jsonData, &item)
 		if err != nil {
-			fmt.Printf("Failed to parse Bitwarden JSON: %v\n", err)
+			return "", "", fmt.Errorf("Failed to parse Bitwarden JSON: %v", err)
 		}
 
 		username = item.Login.Username
 		password = item.Login.Password
 	}
 
-	cluster, err := GetCurrentContext()
-	if err != nil {
-		fmt.Printf("Failed to get current kube context: %v\n", err)
-	}
-
-	shortCluster := cluster
-
-	if fqdn != "" {
-		cluster = cluster + "." + fqdn
-	}
-
-	if clcFQDN != "" {
-		cluster = cluster + "." + clcFQDN
-	}
-
-	if os.Getenv("PROMETHEUS_URL") != "" {
-		prometheus = os.Getenv("PROMETHEUS_URL")
-	}
-
-	if os.Getenv("CLUSTER") != "" {
-		cluster = os.Getenv("CLUSTER")
-	}
-
-	queries := []PrometheusQueries{
-		{
-			Description: "APISERVER",
-			Query:       `avg(up{job="kube-apiserver",cluster="` + cluster + `"})`,
-		},
-		{
-			Description: "CLUSTER",
-			Query:       `capi_cluster_status_phase{phase="Provisioned", tenantcluster="` + shortCluster + `"} == 1`,
-		},
-                {
-                        Description: "FLUENTBIT_OK",
-                        Query: `count(max(fluentbit_output_errors_total{cluster="` + cluster + `"}) + 1)`,
-                },
-                {
-                        Description: "FLUENTD_OK",
-                        Query: `count(max(fluentd_output_status_num_errors{cluster="` + cluster + `"}) + 1)`,
-                },
-		{
-			Description: "GOLDPINGER",
-			Query:       `avg(goldpinger_cluster_health_total{cluster="` + cluster + `"})`,
-		},
-		{
-			Description: "KUBEDNS",
-			Query:       `avg(up{job="kube-dns", cluster="` + cluster + `"})`,
-		},
-		{
-			Description: "KUBELET",
-			Query:       `clamp((count(up{job="kubelet", cluster="` + cluster + `"}) > 3),1,1)`,
-		},
-		{
-			Description: "NETWORKOPERATOR",
-			Query:       `clamp(avg(nwop_netlink_routes_fib{protocol="bgp",vrf="main",cluster="` + cluster + `"}),1,1)`,
-		},
-		{
-			Description: "NODE",
-			Query:       `min(kube_node_status_condition{condition="Ready",status="true",cluster="` + cluster + `"})`,
-		},
-		{
-			Description: "STORAGECHECK",
-			Query:       `clamp((increase(storage_check_success_total{cluster="` + cluster + `"}[1h]) > 1),1,1) OR (storage_check_failure_total{cluster="` + cluster + `"} > 0)`,
-		},
-		{
-			Description: "PROMETHEUSAGENT",
-			Query:       `avg(up{job="prometheus-agent",cluster="` + cluster + `"})`,
-		},
-		{
-			Description: "SYSTEMPODS",
-			Query:       `clamp(sum(kube_pod_status_phase{namespace=~".*-system", phase!~"Running|Succeeded",cluster="` + cluster + `"} == 0),1,1)`,
-		},
-	}
-
-	fmt.Printf("\033[36mclustercheck \033[0m on %s\n", cluster)
-	for _, query := range queries {
-		result, err := QueryPrometheus(prometheus, query.Query, username, password, debug)
-		if err != nil {
-			fmt.Println("Error query :", query.Description, err)
-		} else {
-			if result == "1" {
-			        fmt.Printf("%s \033[32m🟢 OK (1)\033[0m \n", query.Description)
-			} else {
-				fmt.Printf("%s \033[31m🔴 FAIL (0)\033[0m \n", query.Description)
-			}
-		}
-	}
+	return username, password, nil
 }
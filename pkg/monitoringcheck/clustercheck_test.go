@@ -1,13 +1,17 @@
 package monitoringcheck
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
 )
 
 func TestQueryPrometheus(t *testing.T) {
@@ -131,7 +135,7 @@ func TestQueryPrometheus(t *testing.T) {
 			// Build prometheus URL
 			prometheusURL := server.URL + "/api/v1/query"
 
-			result, err := QueryPrometheus(prometheusURL, "test_query", tt.username, tt.password, false)
+			result, err := QueryPrometheus(context.Background(), prometheusURL, "test_query", tt.username, tt.password, false)
 
 			if tt.expectedError {
 				if err == nil {
@@ -150,10 +154,74 @@ func TestQueryPrometheus(t *testing.T) {
 	}
 }
 
+func TestQueryPrometheusSamples(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("query") {
+		case "bad_query":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+		default:
+			w.WriteHeader(200)
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"instance":"a"},"value":[0,"1"]},
+				{"metric":{"instance":"b"},"value":[0,"NaN"]}]}}`))
+		}
+	}))
+	defer server.Close()
+
+	samples, err := QueryPrometheusSamples(context.Background(), server.URL, "up", "", "", false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(samples))
+	}
+	if samples[1].Metric["instance"] != "b" || samples[1].Value != "NaN" {
+		t.Errorf("Unexpected sample: %+v", samples[1])
+	}
+
+	_, err = QueryPrometheusSamples(context.Background(), server.URL, "bad_query", "", "", false)
+	if err == nil || !strings.Contains(err.Error(), "parse error") {
+		t.Errorf("Expected query error, got: %v", err)
+	}
+}
+
+func TestQueryPrometheusRange(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query_range" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		params := r.URL.Query()
+		if params.Get("start") != "1700000000" || params.Get("end") != "1700001800" || params.Get("step") != "60" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","error":"unexpected parameters ` + params.Encode() + `"}`))
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"job":"apiserver"},"values":[[1700000000,"1"],[1700000060,"1"]]}]}}`))
+	}))
+	defer server.Close()
+
+	start := time.Unix(1700000000, 0)
+	samples, err := QueryPrometheusRange(context.Background(), server.URL, "up", start, start.Add(30*time.Minute), time.Minute, "", "", false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(samples) != 1 || len(samples[0].Values) != 2 {
+		t.Errorf("Expected one series with 2 values, got %+v", samples)
+	}
+}
+
 func TestQueryPrometheusTimeout(t *testing.T) {
 	// Create a server that delays response beyond timeout
 	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		time.Sleep(15 * time.Second) // Longer than the 10 second timeout
+		select {
+		case <-time.After(15 * time.Second): // Longer than the 1 second timeout
+		case <-r.Context().Done():
+			return
+		}
 		w.WriteHeader(200)
 		w.Write([]byte(`{"status":"success","data":{"result":[]}}`))
 	}))
@@ -161,9 +229,15 @@ func TestQueryPrometheusTimeout(t *testing.T) {
 
 	prometheusURL := server.URL + "/api/v1/query"
 
-	_, err := QueryPrometheus(prometheusURL, "test_query", "", "", false)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := QueryPrometheus(ctx, prometheusURL, "test_query", "", "", false)
 	if err == nil {
-		t.Error("Expected timeout error, got nil")
+		t.Fatal("Expected timeout error, got nil")
+	}
+	if !checker.IsTimeout(ctx, err) {
+		t.Errorf("Expected error to be reported as timeout, got: %v", err)
 	}
 
 	if !strings.Contains(err.Error(), "timeout") && !strings.Contains(err.Error(), "deadline") {
@@ -172,7 +246,7 @@ func TestQueryPrometheusTimeout(t *testing.T) {
 }
 
 func TestQueryPrometheusInvalidURL(t *testing.T) {
-	_, err := QueryPrometheus("://invalid-url", "test_query", "", "", false)
+	_, err := QueryPrometheus(context.Background(), "://invalid-url", "test_query", "", "", false)
 	if err == nil {
 		t.Error("Expected error for invalid URL, got nil")
 	}
@@ -293,13 +367,13 @@ func TestQueryPrometheusAuthLogic(t *testing.T) {
 	prometheusURL := server.URL + "/api/v1/query"
 
 	// Test with empty username (should NOT set basic auth due to bug)
-	_, err := QueryPrometheus(prometheusURL, "test_query", "", "password", false)
+	_, err := QueryPrometheus(context.Background(), prometheusURL, "test_query", "", "password", false)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
 
 	// Test with non-empty username (should NOT set basic auth due to bug)
-	_, err = QueryPrometheus(prometheusURL, "test_query", "user", "password", false)
+	_, err = QueryPrometheus(context.Background(), prometheusURL, "test_query", "user", "password", false)
 	if err != nil {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -436,7 +510,7 @@ func TestQueryPrometheusResponseParsing(t *testing.T) {
 			defer server.Close()
 
 			prometheusURL := server.URL + "/api/v1/query"
-			result, err := QueryPrometheus(prometheusURL, "test_query", "", "", false)
+			result, err := QueryPrometheus(context.Background(), prometheusURL, "test_query", "", "", false)
 
 			if tt.expectError && err == nil {
 				t.Error("Expected error but got none")
@@ -454,7 +528,7 @@ func TestQueryPrometheusResponseParsing(t *testing.T) {
 func TestQueryPrometheusEdgeCases(t *testing.T) {
 	// Test HTTP request creation failure
 	invalidURL := string([]byte{0x7f})
-	_, err := QueryPrometheus(invalidURL, "test", "", "", false)
+	_, err := QueryPrometheus(context.Background(), invalidURL, "test", "", "", false)
 	if err == nil {
 		t.Error("Expected error for invalid URL characters")
 	}
@@ -467,7 +541,7 @@ func TestQueryPrometheusEdgeCases(t *testing.T) {
 	}))
 	defer server.Close()
 
-	_, err = QueryPrometheus(server.URL+"/api/v1/query", longQuery, "", "", false)
+	_, err = QueryPrometheus(context.Background(), server.URL+"/api/v1/query", longQuery, "", "", false)
 	if err != nil {
 		t.Errorf("Unexpected error with long query: %v", err)
 	}
@@ -475,7 +549,7 @@ func TestQueryPrometheusEdgeCases(t *testing.T) {
 
 func TestQueryPrometheusNetworkErrors(t *testing.T) {
 	// Test connection refused
-	_, err := QueryPrometheus("https://localhost:99999/api/v1/query", "test", "", "", false)
+	_, err := QueryPrometheus(context.Background(), "https://localhost:99999/api/v1/query", "test", "", "", false)
 	if err == nil {
 		t.Error("Expected connection error")
 	}
@@ -489,7 +563,7 @@ func TestQueryPrometheusNetworkErrors(t *testing.T) {
 
 	// Replace http with https to force TLS error on plain HTTP server
 	httpsURL := strings.Replace(server.URL, "http://", "https://", 1)
-	_, err = QueryPrometheus(httpsURL+"/api/v1/query", "test", "", "", false)
+	_, err = QueryPrometheus(context.Background(), httpsURL+"/api/v1/query", "test", "", "", false)
 	if err == nil {
 		t.Error("Expected TLS error")
 	}
@@ -548,10 +622,321 @@ func BenchmarkQueryPrometheus(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := QueryPrometheus(prometheusURL, "test_query", "", "", false)
+		_, err := QueryPrometheus(context.Background(), prometheusURL, "test_query", "", "", false)
 		if err != nil {
 			b.Fatalf("Unexpected error: %v", err)
 		}
 	}
 }
 
+func TestCheckerRun(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := "1"
+		if strings.Contains(r.URL.Query().Get("query"), "goldpinger") {
+			value = "0"
+		}
+		if strings.Contains(r.URL.Query().Get("query"), "kubelet") {
+			value = "5"
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"` + value + `"]}]}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("PROMETHEUS_URL", server.URL)
+	t.Setenv("CLUSTER", "test-cluster")
+	t.Setenv("CLUSTERCHECK_BW", "")
+
+	c := NewChecker(checker.Options{PrometheusTLS: testTLS})
+	if c.Category() != checker.CategoryPrometheus {
+		t.Errorf("Expected category '%s', got '%s'", checker.CategoryPrometheus, c.Category())
+	}
+
+	config, err := DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to load default config: %v", err)
+	}
+
+	results := c.Run(context.Background())
+	if len(results) != len(config.Checks) {
+		t.Fatalf("Expected one result per query, got %d", len(results))
+	}
+
+	for _, result := range results {
+		if result.Name == "GOLDPINGER" {
+			if result.Passed {
+				t.Error("Expected GOLDPINGER to fail")
+			}
+			if result.Message != "Value: 0 (expected: 1)" {
+				t.Errorf("Unexpected message: %s", result.Message)
+			}
+		} else if !result.Passed {
+			t.Errorf("Expected %s to pass, got: %s", result.Name, result.Message)
+		}
+	}
+}
+
+func TestCheckerRunConcurrent(t *testing.T) {
+	var running, maxRunning int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"1"]}]}}`))
+	}))
+	defer server.Close()
+
+	config := "replaceDefaults: true\nchecks:\n"
+	names := []string{"A", "B", "C", "D", "E", "F", "G", "H"}
+	for _, name := range names {
+		config += "- name: " + name + "\n  query: up\n"
+	}
+
+	t.Setenv("PROMETHEUS_URL", server.URL)
+	t.Setenv("CLUSTER", "test-cluster")
+	t.Setenv("CLUSTERCHECK_BW", "")
+
+	c := NewChecker(checker.Options{PrometheusTLS: testTLS, ConfigFile: writeConfig(t, config), Parallelism: 3})
+	started := time.Now()
+	results := c.Run(context.Background())
+	elapsed := time.Since(started)
+
+	if len(results) != len(names) {
+		t.Fatalf("Expected %d results, got %d", len(names), len(results))
+	}
+	for i, result := range results {
+		if result.Name != names[i] {
+			t.Errorf("Expected result %d to be %s, got %s", i, names[i], result.Name)
+		}
+		if !result.Passed {
+			t.Errorf("Expected %s to pass, got: %s", result.Name, result.Message)
+		}
+	}
+
+	if max := atomic.LoadInt32(&maxRunning); max < 2 || max > 3 {
+		t.Errorf("Expected 2 to 3 concurrent queries, got %d", max)
+	}
+	if elapsed >= time.Duration(len(names))*100*time.Millisecond {
+		t.Errorf("Expected queries to run concurrently, took %s", elapsed)
+	}
+}
+
+func TestCheckerRunTimeout(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Contains(r.URL.Query().Get("query"), "slow") {
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"0"]}]}}`))
+	}))
+	defer server.Close()
+
+	config := `
+replaceDefaults: true
+checks:
+- name: SLOW
+  query: slow_metric
+  timeout: 200ms
+- name: FAILING
+  query: up
+`
+
+	t.Setenv("PROMETHEUS_URL", server.URL)
+	t.Setenv("CLUSTER", "test-cluster")
+	t.Setenv("CLUSTERCHECK_BW", "")
+
+	c := NewChecker(checker.Options{PrometheusTLS: testTLS, ConfigFile: writeConfig(t, config), CheckTimeout: 10 * time.Second})
+	results := c.Run(context.Background())
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+
+	if !results[0].TimedOut || results[0].Passed || !strings.HasPrefix(results[0].Message, "Query timed out after") {
+		t.Errorf("Expected SLOW to time out, got %+v", results[0])
+	}
+	if results[1].TimedOut || results[1].Passed {
+		t.Errorf("Expected FAILING to fail without timeout, got %+v", results[1])
+	}
+	if results[0].Duration > 5*time.Second {
+		t.Errorf("Expected the per-check timeout to abort the query, took %s", results[0].Duration)
+	}
+}
+
+func TestCheckerRunRetry(t *testing.T) {
+	var requests int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		if query == "flaky" && atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"error","errorType":"unavailable","error":"starting up"}`))
+			return
+		}
+		if query == "down" {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("Bad Gateway"))
+			return
+		}
+		if query == "invalid" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"1"]}]}}`))
+	}))
+	defer server.Close()
+
+	config := `
+replaceDefaults: true
+checks:
+- name: FLAKY
+  query: flaky
+- name: DOWN
+  query: down
+- name: INVALID
+  query: invalid
+`
+
+	t.Setenv("PROMETHEUS_URL", server.URL)
+	t.Setenv("CLUSTER", "test-cluster")
+	t.Setenv("CLUSTERCHECK_BW", "")
+
+	c := NewChecker(checker.Options{PrometheusTLS: testTLS, ConfigFile: writeConfig(t, config), Retries: 2, RetryBackoff: time.Millisecond})
+	results := c.Run(context.Background())
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+
+	if !results[0].Passed || results[0].Attempts != 2 {
+		t.Errorf("Expected FLAKY to pass on the second attempt, got %+v", results[0])
+	}
+	if results[1].Passed || results[1].Attempts != 3 || !strings.Contains(results[1].Message, "after 3 attempts") ||
+		!strings.Contains(results[1].Message, "502") {
+		t.Errorf("Expected DOWN to fail after 3 attempts, got %+v", results[1])
+	}
+	if results[2].Passed || results[2].Attempts != 1 {
+		t.Errorf("Expected INVALID not to be retried, got %+v", results[2])
+	}
+}
+
+func TestCheckerRunAuth(t *testing.T) {
+	var header atomic.Value
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != header.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"status":"error","error":"unauthorized"}`))
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"1"]}]}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("PROMETHEUS_URL", server.URL)
+	t.Setenv("CLUSTER", "test-cluster")
+	t.Setenv("CLUSTERCHECK_BW", "")
+	t.Setenv("PROM_TOKEN_FILE", "")
+
+	checks := "replaceDefaults: true\nchecks:\n- name: UP\n  query: up\n"
+	tests := []struct {
+		name   string
+		token  string
+		config string
+		header string
+	}{
+		{"environment token", "env-token", checks, "Bearer env-token"},
+		{"endpoint token", "env-token", "endpoints:\n- url: " + server.URL + "\n  auth:\n    type: bearer\n    token: endpoint-token\n" + checks, "Bearer endpoint-token"},
+		{"other endpoint", "env-token", "endpoints:\n- url: https://thanos.example.com\n  auth:\n    type: none\n" + checks, "Bearer env-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PROM_TOKEN", tt.token)
+			header.Store(tt.header)
+
+			c := NewChecker(checker.Options{PrometheusTLS: testTLS, ConfigFile: writeConfig(t, tt.config)})
+			results := c.Run(context.Background())
+			if len(results) != 1 || !results[0].Passed {
+				t.Errorf("Expected UP to pass, got %+v", results)
+			}
+		})
+	}
+}
+
+func TestCheckerRunTenant(t *testing.T) {
+	var headers atomic.Value
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers.Store(r.Header.Clone())
+		if r.Header.Get("X-Scope-OrgID") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"status":"error","error":"no org id"}`))
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"1"]}]}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("PROMETHEUS_URL", server.URL)
+	t.Setenv("CLUSTER", "test-cluster")
+	t.Setenv("CLUSTERCHECK_BW", "")
+
+	checks := "replaceDefaults: true\nchecks:\n- name: UP\n  query: up\n"
+	tests := []struct {
+		name   string
+		tenant string
+		config string
+		want   http.Header
+	}{
+		{"endpoint tenant and headers", "", "endpoints:\n- url: " + server.URL + "\n  tenant: 'team-{{.Cluster}}'\n  headers:\n    X-Team: platform\n" + checks,
+			http.Header{"X-Scope-Orgid": {"team-test-cluster"}, "X-Team": {"platform"}}},
+		{"environment tenant", "{{.Cluster}}", checks, http.Header{"X-Scope-Orgid": {"test-cluster"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PROM_TENANT", tt.tenant)
+
+			results := NewChecker(checker.Options{PrometheusTLS: testTLS, ConfigFile: writeConfig(t, tt.config)}).Run(context.Background())
+			if len(results) != 1 || !results[0].Passed {
+				t.Errorf("Expected UP to pass, got %+v", results)
+			}
+			received := headers.Load().(http.Header)
+			for name := range tt.want {
+				if received.Get(name) != tt.want.Get(name) {
+					t.Errorf("Expected header %s %q, got %q", name, tt.want.Get(name), received.Get(name))
+				}
+			}
+		})
+	}
+}
+
+func TestForwardedEndpoint(t *testing.T) {
+	t.Setenv("PROM_TOKEN", "env-token")
+	t.Setenv("PROM_TOKEN_FILE", "")
+	t.Setenv("PROM_TENANT", "env-tenant")
+
+	endpoint := forwardedEndpoint(&Config{}, "https://127.0.0.1:9090")
+	if endpoint.URL != "https://127.0.0.1:9090" || endpoint.Auth.Type != AuthBearer || endpoint.Tenant != "env-tenant" {
+		t.Errorf("Expected the authentication and tenant of the environment, got %+v", endpoint)
+	}
+
+	config := &Config{Endpoints: []Endpoint{{
+		URL:     "https://mimir.example.com",
+		Auth:    AuthConfig{Type: AuthNone},
+		Tenant:  "{{.ShortCluster}}",
+		Headers: map[string]string{"X-Team": "platform"},
+	}}}
+	endpoint = forwardedEndpoint(config, "https://127.0.0.1:9090")
+	if endpoint.URL != "https://127.0.0.1:9090" || endpoint.Auth.Type != AuthNone || endpoint.Tenant != "{{.ShortCluster}}" || endpoint.Headers["X-Team"] != "platform" {
+		t.Errorf("Expected the authentication, tenant and headers of the configured endpoint, got %+v", endpoint)
+	}
+}
@@ -0,0 +1,339 @@
+package monitoringcheck
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultStep is the resolution of range queries without step
+const defaultStep = time.Minute
+
+// maxRangePoints is the Prometheus limit of points per series of a range query
+const maxRangePoints = 11000
+
+// defaultConfig holds the built-in Prometheus checks
+//
+//go:embed checks.yaml
+var defaultConfig []byte
+
+// Config is the Prometheus checks configuration file
+type Config struct {
+	// Endpoints configures the authentication of Prometheus endpoints. The
+	// endpoints matching PROMETHEUS_URL are used, or all endpoints in the
+	// order of the list if PROMETHEUS_URL is not set.
+	Endpoints []Endpoint `json:"endpoints,omitempty"`
+	// ClusterLookup configures the lookup of the cluster label in Prometheus
+	// by the API server address, see -cluster-lookup
+	ClusterLookup ClusterLookup `json:"clusterLookup,omitempty"`
+	// ReplaceDefaults drops all built-in checks instead of merging the checks into them
+	ReplaceDefaults bool `json:"replaceDefaults,omitempty"`
+	// Checks are added to the built-in checks, checks with the name of a built-in check override it
+	Checks []QueryCheck `json:"checks"`
+}
+
+// Endpoint is a Prometheus API endpoint
+type Endpoint struct {
+	// URL is the base URL of the Prometheus API, e.g. https://prometheus.example.com
+	URL  string     `json:"url"`
+	Auth AuthConfig `json:"auth,omitempty"`
+	// Tenant is sent as X-Scope-OrgID header to multi-tenant query frontends
+	// like Mimir, Cortex or Thanos. It is a Go template rendered with the
+	// cluster labels like the queries, e.g. "{{.ShortCluster}}".
+	Tenant string `json:"tenant,omitempty"`
+	// Headers are sent with every request and override the headers of the
+	// authentication. The values are Go templates like Tenant.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// TenantHeader is the header of the tenant of multi-tenant query frontends
+const TenantHeader = "X-Scope-OrgID"
+
+// QueryCheck describes a single Prometheus health check
+type QueryCheck struct {
+	Name string `json:"name"`
+	// Query is a Go template rendered with the cluster labels, see QueryData
+	Query string `json:"query"`
+	// Expect is the expected result of the query, e.g. "1", ">= 3", "< 0.05",
+	// "0.9..1" or "empty", see Expectation. Defaults to "1".
+	Expect string `json:"expect,omitempty"`
+	// Range turns the check into a range query over the last duration, e.g.
+	// "30m". Every value of the range has to match the expectation.
+	Range string `json:"range,omitempty"`
+	// Step is the resolution of a range query, defaults to 1m
+	Step string `json:"step,omitempty"`
+	// Timeout limits the duration of the query, e.g. "1m". Defaults to the
+	// check timeout of the run.
+	Timeout string `json:"timeout,omitempty"`
+	// Severity is either critical (default) or warning. Failed warning checks
+	// are reported but do not lower the health score.
+	Severity string `json:"severity,omitempty"`
+	// Disabled drops a built-in check with the same name
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// QueryData is passed to the query templates. The values are escaped for use
+// inside double-quoted PromQL label matchers.
+type QueryData struct {
+	Cluster      string
+	ShortCluster string
+}
+
+// DefaultConfig returns the built-in Prometheus checks
+func DefaultConfig() (*Config, error) {
+	return parseConfig(defaultConfig)
+}
+
+// LoadConfig loads the Prometheus checks from a configuration file and merges
+// them with the built-in checks. Without path only the built-in checks are returned.
+func LoadConfig(path string) (*Config, error) {
+	config, err := DefaultConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse built-in checks: %v", err)
+	}
+
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %v", path, err)
+	}
+
+	custom, err := parseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+
+	return config.Merge(custom), nil
+}
+
+func parseConfig(data []byte) (*Config, error) {
+	config := &Config{}
+	if err := yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, err
+	}
+
+	for i, endpoint := range config.Endpoints {
+		if endpoint.URL == "" {
+			return nil, fmt.Errorf("endpoint %d has no url", i+1)
+		}
+		if err := endpoint.Auth.Validate(); err != nil {
+			return nil, fmt.Errorf("endpoint %s: %v", endpoint.URL, err)
+		}
+		if _, err := endpoint.RenderHeaders("", ""); err != nil {
+			return nil, fmt.Errorf("endpoint %s: %v", endpoint.URL, err)
+		}
+	}
+
+	names := map[string]bool{}
+	for i, check := range config.Checks {
+		if check.Name == "" {
+			return nil, fmt.Errorf("check %d has no name", i+1)
+		}
+		// a check is overridden by name, so a second one would be ambiguous
+		if names[check.Name] {
+			return nil, fmt.Errorf("check %s is defined more than once", check.Name)
+		}
+		names[check.Name] = true
+		if check.Query == "" && !check.Disabled {
+			return nil, fmt.Errorf("check %s has no query", check.Name)
+		}
+		if check.Severity != "" && check.Severity != checker.SeverityCritical && check.Severity != checker.SeverityWarning {
+			return nil, fmt.Errorf("check %s has unknown severity %q", check.Name, check.Severity)
+		}
+		if _, _, err := check.RangeStep(); err != nil {
+			return nil, fmt.Errorf("check %s: %v", check.Name, err)
+		}
+		if _, err := check.QueryTimeout(0); err != nil {
+			return nil, fmt.Errorf("check %s: %v", check.Name, err)
+		}
+		if _, err := check.Expectation(); err != nil {
+			return nil, fmt.Errorf("check %s: %v", check.Name, err)
+		}
+		if _, err := template.New(check.Name).Parse(check.Query); err != nil {
+			return nil, fmt.Errorf("check %s has invalid query template: %v", check.Name, err)
+		}
+	}
+
+	return config, nil
+}
+
+// Merge returns the checks of c overridden and extended by the checks of
+// custom. Disabled checks are dropped. The endpoints and the cluster lookup of
+// custom replace the ones of c.
+func (c *Config) Merge(custom *Config) *Config {
+	merged := &Config{Endpoints: c.Endpoints, ClusterLookup: c.ClusterLookup, Checks: []QueryCheck{}}
+	if len(custom.Endpoints) > 0 {
+		merged.Endpoints = custom.Endpoints
+	}
+	if custom.ClusterLookup != (ClusterLookup{}) {
+		merged.ClusterLookup = custom.ClusterLookup
+	}
+
+	base := c.Checks
+	if custom.ReplaceDefaults {
+		base = nil
+	}
+
+	overrides := map[string]QueryCheck{}
+	for _, check := range custom.Checks {
+		overrides[check.Name] = check
+	}
+
+	for _, check := range base {
+		if override, ok := overrides[check.Name]; ok {
+			check = override
+			delete(overrides, check.Name)
+		}
+		if !check.Disabled {
+			merged.Checks = append(merged.Checks, check)
+		}
+	}
+
+	for _, check := range custom.Checks {
+		if _, ok := overrides[check.Name]; ok && !check.Disabled {
+			merged.Checks = append(merged.Checks, check)
+			delete(overrides, check.Name)
+		}
+	}
+
+	return merged
+}
+
+// Endpoint returns the configured endpoint of the given URL. The returned
+// bool is false if the endpoint is not configured.
+func (c *Config) Endpoint(url string) (Endpoint, bool) {
+	for _, endpoint := range c.Endpoints {
+		if strings.TrimSuffix(endpoint.URL, "/") == strings.TrimSuffix(url, "/") {
+			return endpoint, true
+		}
+	}
+	return Endpoint{URL: url}, false
+}
+
+// Expectation returns the parsed expected result of the check
+func (q QueryCheck) Expectation() (Expectation, error) {
+	if q.Expect == "" {
+		return ParseExpectation("1")
+	}
+	return ParseExpectation(q.Expect)
+}
+
+// RangeStep returns the range and step of a range query check. The range is
+// zero for instant query checks.
+func (q QueryCheck) RangeStep() (time.Duration, time.Duration, error) {
+	if q.Range == "" {
+		if q.Step != "" {
+			return 0, 0, fmt.Errorf("step requires a range")
+		}
+		return 0, 0, nil
+	}
+
+	queryRange, err := time.ParseDuration(q.Range)
+	if err != nil || queryRange <= 0 {
+		return 0, 0, fmt.Errorf("invalid range %q", q.Range)
+	}
+
+	step := defaultStep
+	if q.Step != "" {
+		step, err = time.ParseDuration(q.Step)
+		if err != nil || step <= 0 {
+			return 0, 0, fmt.Errorf("invalid step %q", q.Step)
+		}
+	}
+	if queryRange/step > maxRangePoints {
+		return 0, 0, fmt.Errorf("range %s with step %s exceeds %d points per series", queryRange, step, maxRangePoints)
+	}
+	return queryRange, step, nil
+}
+
+// QueryTimeout returns the timeout of the query, or defaultTimeout if the
+// check sets none
+func (q QueryCheck) QueryTimeout(defaultTimeout time.Duration) (time.Duration, error) {
+	if q.Timeout == "" {
+		return defaultTimeout, nil
+	}
+	timeout, err := time.ParseDuration(q.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q", q.Timeout)
+	}
+	return timeout, nil
+}
+
+// Render returns the query of the check for the given cluster labels
+func (q QueryCheck) Render(cluster string, shortCluster string) (string, error) {
+	return render(q.Name, q.Query, QueryData{
+		Cluster:      escapeLabelValue(cluster),
+		ShortCluster: escapeLabelValue(shortCluster),
+	})
+}
+
+// RenderHeaders returns the tenant and custom headers of the endpoint rendered
+// with the cluster labels. The values are not escaped.
+func (e Endpoint) RenderHeaders(cluster string, shortCluster string) (http.Header, error) {
+	data := QueryData{Cluster: cluster, ShortCluster: shortCluster}
+	headers := http.Header{}
+
+	for name, value := range e.Headers {
+		if !validHeaderName(name) {
+			return nil, fmt.Errorf("invalid header name %q", name)
+		}
+		rendered, err := render(name, value, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header %s: %v", name, err)
+		}
+		headers.Set(name, rendered)
+	}
+
+	if e.Tenant != "" {
+		tenant, err := render(TenantHeader, e.Tenant, data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tenant: %v", err)
+		}
+		headers.Set(TenantHeader, tenant)
+	}
+
+	return headers, nil
+}
+
+// render executes a Go template with the cluster labels
+func render(name string, text string, data QueryData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// validHeaderName reports whether name is a non-empty HTTP header token
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeLabelValue escapes a value for use inside a double-quoted PromQL string
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
@@ -0,0 +1,267 @@
+package monitoringcheck
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "checks.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func checkNames(config *Config) []string {
+	names := []string{}
+	for _, check := range config.Checks {
+		names = append(names, check.Name)
+	}
+	return names
+}
+
+func TestDefaultConfig(t *testing.T) {
+	config, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("Failed to load built-in checks: %v", err)
+	}
+
+	if len(config.Checks) != 12 {
+		t.Errorf("Expected 12 built-in checks, got %d: %v", len(config.Checks), checkNames(config))
+	}
+
+	for _, check := range config.Checks {
+		if check.Severity != checker.SeverityCritical {
+			t.Errorf("Expected built-in check %s to be critical, got %q", check.Name, check.Severity)
+		}
+		if _, err := check.Render("cluster.example.com", "cluster"); err != nil {
+			t.Errorf("Failed to render built-in check %s: %v", check.Name, err)
+		}
+	}
+}
+
+func TestLoadConfigMerge(t *testing.T) {
+	path := writeConfig(t, `
+checks:
+- name: GOLDPINGER
+  disabled: true
+- name: NETWORKOPERATOR
+  disabled: true
+- name: NODE
+  query: 'min(kube_node_status_condition{cluster="{{.Cluster}}",condition="Ready",status="true"})'
+  severity: warning
+- name: CERTMANAGER
+  query: 'up{cluster="{{.Cluster}}",job="cert-manager"}'
+`)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	names := strings.Join(checkNames(config), ",")
+	if strings.Contains(names, "GOLDPINGER") || strings.Contains(names, "NETWORKOPERATOR") {
+		t.Errorf("Expected disabled checks to be dropped, got %s", names)
+	}
+	if len(config.Checks) != 11 {
+		t.Errorf("Expected 11 checks, got %d: %s", len(config.Checks), names)
+	}
+	if config.Checks[len(config.Checks)-1].Name != "CERTMANAGER" {
+		t.Errorf("Expected new check to be appended, got %s", names)
+	}
+
+	for _, check := range config.Checks {
+		if check.Name == "NODE" {
+			if check.Severity != checker.SeverityWarning {
+				t.Errorf("Expected NODE to be overridden with severity warning, got %q", check.Severity)
+			}
+			if !strings.Contains(check.Query, "kube_node_status_condition") {
+				t.Errorf("Expected NODE query to be overridden, got %s", check.Query)
+			}
+		}
+	}
+}
+
+func TestLoadConfigReplaceDefaults(t *testing.T) {
+	path := writeConfig(t, `
+replaceDefaults: true
+clusterLookup:
+  query: 'group by (cluster, instance) (up{job="apiserver"})'
+checks:
+- name: UP
+  query: 'min(up{cluster="{{.Cluster}}"})'
+  expect: "1"
+`)
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	if len(config.Checks) != 1 || config.Checks[0].Name != "UP" {
+		t.Errorf("Expected only check UP, got %v", checkNames(config))
+	}
+	if lookup := config.ClusterLookup.withDefaults(); lookup.Query != `group by (cluster, instance) (up{job="apiserver"})` || lookup.Label != "cluster" {
+		t.Errorf("Expected the cluster lookup to be merged, got %+v", lookup)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errText string
+	}{
+		{"missing name", "checks:\n- query: up\n", "has no name"},
+		{"missing query", "checks:\n- name: UP\n", "has no query"},
+		{"duplicate name", "checks:\n- name: UP\n  query: up\n- name: UP\n  disabled: true\n", "defined more than once"},
+		{"unknown severity", "checks:\n- name: UP\n  query: up\n  severity: major\n", "unknown severity"},
+		{"invalid template", "checks:\n- name: UP\n  query: 'up{cluster=\"{{.Cluster\"}'\n", "invalid query template"},
+		{"invalid expectation", "checks:\n- name: UP\n  query: up\n  expect: '>= three'\n", "invalid expectation"},
+		{"invalid range", "checks:\n- name: UP\n  query: up\n  range: 30 minutes\n", "invalid range"},
+		{"invalid step", "checks:\n- name: UP\n  query: up\n  range: 30m\n  step: -1s\n", "invalid step"},
+		{"step without range", "checks:\n- name: UP\n  query: up\n  step: 1m\n", "step requires a range"},
+		{"too many points", "checks:\n- name: UP\n  query: up\n  range: 720h\n  step: 1m\n", "exceeds"},
+		{"unknown field", "checks:\n- name: UP\n  query: up\n  expected: \"1\"\n", "unknown field"},
+		{"invalid tenant template", "endpoints:\n- url: https://mimir\n  tenant: '{{.Cluster'\n", "invalid tenant"},
+		{"invalid header name", "endpoints:\n- url: https://mimir\n  headers:\n    'X Team': a\n", "invalid header name"},
+		{"unknown header template field", "endpoints:\n- url: https://mimir\n  headers:\n    X-Team: '{{.Team}}'\n", "invalid header X-Team"},
+		{"endpoint without url", "endpoints:\n- auth:\n    type: none\n", "has no url"},
+		{"unknown auth type", "endpoints:\n- url: https://prometheus\n  auth:\n    type: digest\n", "unknown auth type"},
+		{"bearer without token", "endpoints:\n- url: https://prometheus\n  auth:\n    type: bearer\n", "requires a token"},
+		{"oauth2 without client", "endpoints:\n- url: https://prometheus\n  auth:\n    type: oauth2\n    oauth2:\n      tokenURL: https://sso/token\n", "requires tokenURL and clientID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tt.content))
+			if err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errText) {
+				t.Errorf("Expected error containing %q, got: %v", tt.errText, err)
+			}
+		})
+	}
+
+	if _, err := LoadConfig("/nonexistent/checks.yaml"); err == nil {
+		t.Error("Expected error for missing config file, got nil")
+	}
+}
+
+func TestConfigEndpoint(t *testing.T) {
+	config, err := LoadConfig(writeConfig(t, `
+endpoints:
+- url: https://prometheus.example.com/
+  auth:
+    type: bearer
+    tokenFile: /var/run/secrets/token
+- url: https://thanos.example.com
+  auth:
+    type: oauth2
+    oauth2:
+      tokenURL: https://sso.example.com/token
+      clientID: clustercheck
+      clientSecret: secret
+      scopes: [metrics]
+checks: []
+`))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	tests := []struct {
+		url        string
+		configured bool
+		authType   string
+	}{
+		{"https://prometheus.example.com", true, AuthBearer},
+		{"https://thanos.example.com/", true, AuthOAuth2},
+		{"https://other.example.com", false, ""},
+	}
+
+	for _, tt := range tests {
+		endpoint, configured := config.Endpoint(tt.url)
+		if configured != tt.configured || endpoint.Auth.Type != tt.authType {
+			t.Errorf("Endpoint(%q): expected configured %v with auth %q, got %v with %+v", tt.url, tt.configured, tt.authType, configured, endpoint)
+		}
+	}
+
+	defaults, _ := DefaultConfig()
+	if len(config.Checks) != len(defaults.Checks) {
+		t.Errorf("Expected the built-in checks to be kept, got %v", checkNames(config))
+	}
+}
+
+func TestEndpointRenderHeaders(t *testing.T) {
+	endpoint := Endpoint{
+		URL:    "https://mimir.example.com/prometheus",
+		Tenant: "{{.ShortCluster}}",
+		Headers: map[string]string{
+			"X-Cluster":     "{{.Cluster}}",
+			"x-api-key":     "secret",
+			"X-Scope-OrgID": "overridden by tenant",
+		},
+	}
+
+	headers, err := endpoint.RenderHeaders(`my"cluster.example.com`, "my-cluster")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if headers.Get(TenantHeader) != "my-cluster" {
+		t.Errorf("Expected tenant my-cluster, got %q", headers.Get(TenantHeader))
+	}
+	if headers.Get("X-Cluster") != `my"cluster.example.com` {
+		t.Errorf("Expected unescaped cluster header, got %q", headers.Get("X-Cluster"))
+	}
+	if headers.Get("X-Api-Key") != "secret" {
+		t.Errorf("Expected X-Api-Key header, got %q", headers.Get("X-Api-Key"))
+	}
+
+	headers, err = Endpoint{URL: "https://prometheus.example.com"}.RenderHeaders("cluster", "short")
+	if err != nil || len(headers) != 0 {
+		t.Errorf("Expected no headers, got %v (%v)", headers, err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	check := QueryCheck{
+		Name:  "TEST",
+		Query: `up{cluster="{{.Cluster}}",short="{{.ShortCluster}}"}`,
+	}
+
+	query, err := check.Render(`bad"cluster`, "short")
+	if err != nil {
+		t.Fatalf("Failed to render query: %v", err)
+	}
+	if query != `up{cluster="bad\"cluster",short="short"}` {
+		t.Errorf("Unexpected query: %s", query)
+	}
+
+	check.Query = "up{cluster=\"{{.Unknown}}\"}"
+	if _, err := check.Render("cluster", "short"); err == nil {
+		t.Error("Expected error for unknown template field, got nil")
+	}
+}
+
+func TestRangeStep(t *testing.T) {
+	queryRange, step, err := QueryCheck{Name: "UP", Range: "30m"}.RangeStep()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if queryRange != 30*time.Minute || step != time.Minute {
+		t.Errorf("Expected range 30m with default step 1m, got %s and %s", queryRange, step)
+	}
+
+	queryRange, _, err = QueryCheck{Name: "UP"}.RangeStep()
+	if err != nil || queryRange != 0 {
+		t.Errorf("Expected instant query without range, got %s (%v)", queryRange, err)
+	}
+}
@@ -0,0 +1,192 @@
+package monitoringcheck
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+)
+
+// Strategies of several Prometheus endpoints
+const (
+	// StrategyFailover queries the first healthy endpoint and fails over to
+	// the next one on errors
+	StrategyFailover = "failover"
+	// StrategyCompare queries all endpoints and fails checks on which the
+	// endpoints disagree
+	StrategyCompare = "compare"
+)
+
+// defaultPrometheusURL is the Prometheus endpoint without configuration
+const defaultPrometheusURL = "https://127.0.0.1:9090"
+
+// prometheusEndpoints returns the Prometheus endpoints of PROMETHEUS_URL, a comma-separated
+// list of URLs, or all endpoints of the configuration if PROMETHEUS_URL is not
+// set. Endpoints which are not configured use the authentication and tenant of
+// the environment.
+func prometheusEndpoints(config *Config) []Endpoint {
+	urls := []string{}
+	for _, url := range strings.Split(os.Getenv("PROMETHEUS_URL"), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	if len(urls) == 0 {
+		if len(config.Endpoints) > 0 {
+			return config.Endpoints
+		}
+		urls = []string{defaultPrometheusURL}
+	}
+
+	result := []Endpoint{}
+	for _, url := range urls {
+		endpoint, configured := config.Endpoint(url)
+		if !configured {
+			endpoint.Auth = envAuth()
+			endpoint.Tenant = os.Getenv("PROM_TENANT")
+		}
+		result = append(result, endpoint)
+	}
+	return result
+}
+
+// endpointPool holds the clients of the Prometheus endpoints. Endpoints which
+// failed are tried last by the following queries.
+type endpointPool struct {
+	clients []*Client
+
+	mu     sync.Mutex
+	failed map[*Client]bool
+}
+
+// newEndpointPool creates a pool of the clients in the order of preference
+func newEndpointPool(clients []*Client) *endpointPool {
+	return &endpointPool{clients: clients, failed: map[*Client]bool{}}
+}
+
+// order returns the healthy clients followed by the failed ones
+func (p *endpointPool) order() []*Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	healthy := []*Client{}
+	failed := []*Client{}
+	for _, client := range p.clients {
+		if p.failed[client] {
+			failed = append(failed, client)
+		} else {
+			healthy = append(healthy, client)
+		}
+	}
+	return append(healthy, failed...)
+}
+
+// setFailed marks the endpoint of the client as failed or healthy
+func (p *endpointPool) setFailed(client *Client, failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.failed[client] = failed
+}
+
+// failover queries the endpoints in the order of the pool until one answers.
+// Every endpoint gets an equal share of the time left, so a hanging endpoint
+// does not use up the timeout of the query. Endpoints which are down, time
+// out or are overloaded are marked failed, errors of the query itself like a
+// PromQL parse error are not held against the endpoint.
+func (c *Checker) failover(ctx context.Context, pool *endpointPool, result checker.Result, expect Expectation, query queryFunc) checker.Result {
+	var err error
+	clients := pool.order()
+	for i, client := range clients {
+		attemptCtx, cancel := attemptContext(ctx, len(clients)-i)
+		var samples []Sample
+		var attempts int
+		samples, attempts, err = c.queryEndpoint(attemptCtx, client, query)
+		timedOut := checker.IsTimeout(attemptCtx, err)
+		cancel()
+		result.Attempts += attempts
+		result.Endpoint = client.Name()
+		if err == nil {
+			pool.setFailed(client, false)
+			result.Duration = time.Since(result.StartedAt)
+			return evaluate(result, expect, samples)
+		}
+
+		if timedOut || retryable(err) {
+			pool.setFailed(client, true)
+		}
+		if c.debug && len(pool.clients) > 1 {
+			fmt.Fprintf(os.Stderr, "[DEBUG] Prometheus endpoint %s failed: %v\n", client.Name(), err)
+		}
+		// no time left for the next endpoint
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	result.Duration = time.Since(result.StartedAt)
+	return queryError(ctx, result, err)
+}
+
+// attemptContext returns the context of the query of the next of remaining
+// endpoints with an equal share of the time left until the deadline of ctx
+func attemptContext(ctx context.Context, remaining int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || remaining <= 1 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(remaining))
+}
+
+// compare queries all endpoints concurrently. The check fails if the endpoints
+// disagree whether it passed, e.g. if one endpoint is down or misses data.
+func (c *Checker) compare(ctx context.Context, pool *endpointPool, result checker.Result, expect Expectation, query queryFunc) checker.Result {
+	results := make([]checker.Result, len(pool.clients))
+	var wg sync.WaitGroup
+	for i, client := range pool.clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			samples, attempts, err := c.queryEndpoint(ctx, client, query)
+			r := result
+			r.Attempts = attempts
+			r.Endpoint = client.Name()
+			r.Duration = time.Since(r.StartedAt)
+			if err != nil {
+				results[i] = queryError(ctx, r, err)
+			} else {
+				results[i] = evaluate(r, expect, samples)
+			}
+		}()
+	}
+	wg.Wait()
+
+	combined := results[0]
+	combined.Duration = time.Since(result.StartedAt)
+	names := []string{}
+	messages := []string{}
+	disagree := false
+	for _, r := range results {
+		names = append(names, r.Endpoint)
+		messages = append(messages, fmt.Sprintf("%s: %s", r.Endpoint, r.Message))
+		combined.Attempts = max(combined.Attempts, r.Attempts)
+		combined.TimedOut = combined.TimedOut && r.TimedOut
+		if r.Passed != combined.Passed {
+			disagree = true
+		}
+		if !r.Passed && combined.Passed {
+			combined.Value = r.Value
+			combined.Findings = r.Findings
+		}
+	}
+	combined.Endpoint = strings.Join(names, ", ")
+
+	if disagree {
+		combined.Passed = false
+		combined.Message = "Endpoints disagree: " + strings.Join(messages, "; ")
+	}
+	return combined
+}
@@ -0,0 +1,181 @@
+package monitoringcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+)
+
+func TestPrometheusEndpoints(t *testing.T) {
+	config := &Config{Endpoints: []Endpoint{
+		{URL: "https://prometheus-0.example.com", Auth: AuthConfig{Type: AuthNone}},
+		{URL: "https://prometheus-1.example.com", Auth: AuthConfig{Type: AuthBearer, Token: "token"}},
+	}}
+	t.Setenv("PROM_TOKEN", "")
+	t.Setenv("PROM_TOKEN_FILE", "")
+	t.Setenv("PROM_TENANT", "")
+
+	tests := []struct {
+		name   string
+		env    string
+		config *Config
+		urls   []string
+		auth   []string
+	}{
+		{"default", "", &Config{}, []string{"https://127.0.0.1:9090"}, []string{AuthBasic}},
+		{"configured endpoints", "", config, []string{"https://prometheus-0.example.com", "https://prometheus-1.example.com"}, []string{AuthNone, AuthBearer}},
+		{"list", "https://prometheus-1.example.com, https://prometheus-2.example.com,", config,
+			[]string{"https://prometheus-1.example.com", "https://prometheus-2.example.com"}, []string{AuthBearer, AuthBasic}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PROMETHEUS_URL", tt.env)
+			endpoints := prometheusEndpoints(tt.config)
+			if len(endpoints) != len(tt.urls) {
+				t.Fatalf("Expected %d endpoints, got %+v", len(tt.urls), endpoints)
+			}
+			for i, endpoint := range endpoints {
+				if endpoint.URL != tt.urls[i] || endpoint.Auth.Type != tt.auth[i] {
+					t.Errorf("Expected endpoint %s with auth %s, got %+v", tt.urls[i], tt.auth[i], endpoint)
+				}
+			}
+		})
+	}
+}
+
+// valueServer returns a Prometheus server answering all queries with value and counting the requests
+func valueServer(t *testing.T, status int, value string, requests *int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			w.Write([]byte("unavailable"))
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"` + value + `"]}]}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckerRunFailover(t *testing.T) {
+	var downRequests, upRequests int32
+	down := valueServer(t, http.StatusServiceUnavailable, "", &downRequests)
+	up := valueServer(t, http.StatusOK, "1", &upRequests)
+
+	t.Setenv("PROMETHEUS_URL", down.URL+","+up.URL)
+	t.Setenv("CLUSTER", "test-cluster")
+	t.Setenv("CLUSTERCHECK_BW", "")
+
+	config := writeConfig(t, "replaceDefaults: true\nchecks:\n- name: A\n  query: a\n- name: B\n  query: b\n- name: C\n  query: c\n")
+	c := NewChecker(checker.Options{PrometheusTLS: testTLS, ConfigFile: config, Parallelism: 1, Retries: 1, RetryBackoff: time.Millisecond})
+	results := c.Run(context.Background())
+
+	for _, result := range results {
+		if !result.Passed || result.Endpoint != up.URL {
+			t.Errorf("Expected %s to pass on %s, got %+v", result.Name, up.URL, result)
+		}
+	}
+	if results[0].Attempts != 3 {
+		t.Errorf("Expected 2 attempts on the failed and 1 on the healthy endpoint, got %d", results[0].Attempts)
+	}
+	// the failed endpoint is tried last by the following queries
+	if n := atomic.LoadInt32(&downRequests); n != 2 {
+		t.Errorf("Expected the failed endpoint to be skipped after the first query, got %d requests", n)
+	}
+
+	// all endpoints down
+	t.Setenv("PROMETHEUS_URL", down.URL)
+	results = NewChecker(checker.Options{PrometheusTLS: testTLS, ConfigFile: config}).Run(context.Background())
+	if results[0].Passed || results[0].Endpoint != down.URL || !strings.Contains(results[0].Message, "503") {
+		t.Errorf("Expected A to fail on %s, got %+v", down.URL, results[0])
+	}
+}
+
+func TestCheckerRunFailoverHanging(t *testing.T) {
+	hung := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	t.Cleanup(hung.Close)
+	var upRequests int32
+	up := valueServer(t, http.StatusOK, "1", &upRequests)
+
+	t.Setenv("PROMETHEUS_URL", hung.URL+","+up.URL)
+	t.Setenv("CLUSTER", "test-cluster")
+	t.Setenv("CLUSTERCHECK_BW", "")
+
+	config := writeConfig(t, "replaceDefaults: true\nchecks:\n- name: A\n  query: a\n- name: B\n  query: b\n")
+	c := NewChecker(checker.Options{PrometheusTLS: testTLS, ConfigFile: config, Parallelism: 1, Retries: 0, CheckTimeout: time.Second})
+	results := c.Run(context.Background())
+
+	// the hanging endpoint gets half of the timeout and is tried last by B
+	if !results[0].Passed || results[0].Endpoint != up.URL {
+		t.Errorf("Expected A to fail over to %s, got %+v", up.URL, results[0])
+	}
+	if !results[1].Passed || results[1].Endpoint != up.URL || results[1].Attempts != 1 {
+		t.Errorf("Expected B to pass on %s without trying the hanging endpoint, got %+v", up.URL, results[1])
+	}
+}
+
+func TestCheckerRunFailoverQueryError(t *testing.T) {
+	var badRequests, upRequests int32
+	bad := valueServer(t, http.StatusBadRequest, "", &badRequests)
+	up := valueServer(t, http.StatusOK, "1", &upRequests)
+
+	t.Setenv("PROMETHEUS_URL", bad.URL+","+up.URL)
+	t.Setenv("CLUSTER", "test-cluster")
+	t.Setenv("CLUSTERCHECK_BW", "")
+
+	config := writeConfig(t, "replaceDefaults: true\nchecks:\n- name: A\n  query: a\n- name: B\n  query: b\n")
+	c := NewChecker(checker.Options{PrometheusTLS: testTLS, ConfigFile: config, Parallelism: 1, Retries: 0})
+	results := c.Run(context.Background())
+
+	// a query error does not mark the endpoint failed, B is tried on it first
+	if n := atomic.LoadInt32(&badRequests); n != 2 {
+		t.Errorf("Expected both queries on the first endpoint, got %d requests", n)
+	}
+	if !results[1].Passed || results[1].Attempts != 2 {
+		t.Errorf("Expected B to pass on the second attempt, got %+v", results[1])
+	}
+}
+
+func TestCheckerRunCompare(t *testing.T) {
+	var requests int32
+	healthy := valueServer(t, http.StatusOK, "1", &requests)
+	replica := valueServer(t, http.StatusOK, "1", &requests)
+	lagging := valueServer(t, http.StatusOK, "0", &requests)
+
+	t.Setenv("CLUSTER", "test-cluster")
+	t.Setenv("CLUSTERCHECK_BW", "")
+	config := writeConfig(t, "replaceDefaults: true\nchecks:\n- name: UP\n  query: up\n")
+
+	t.Setenv("PROMETHEUS_URL", healthy.URL+","+replica.URL)
+	results := NewChecker(checker.Options{PrometheusTLS: testTLS, ConfigFile: config, PrometheusStrategy: StrategyCompare}).Run(context.Background())
+	if !results[0].Passed || results[0].Endpoint != healthy.URL+", "+replica.URL {
+		t.Errorf("Expected UP to pass on both endpoints, got %+v", results[0])
+	}
+
+	t.Setenv("PROMETHEUS_URL", healthy.URL+","+lagging.URL)
+	results = NewChecker(checker.Options{PrometheusTLS: testTLS, ConfigFile: config, PrometheusStrategy: StrategyCompare}).Run(context.Background())
+	if results[0].Passed || results[0].Value != "0" || !strings.HasPrefix(results[0].Message, "Endpoints disagree: "+healthy.URL+": Healthy; "+lagging.URL+": Value: 0") {
+		t.Errorf("Expected UP to fail on disagreeing endpoints, got %+v", results[0])
+	}
+
+	// failover queries only the first endpoint
+	atomic.StoreInt32(&requests, 0)
+	results = NewChecker(checker.Options{PrometheusTLS: testTLS, ConfigFile: config}).Run(context.Background())
+	if !results[0].Passed || results[0].Endpoint != healthy.URL || atomic.LoadInt32(&requests) != 1 {
+		t.Errorf("Expected UP to pass on the first endpoint only, got %+v after %d requests", results[0], requests)
+	}
+}
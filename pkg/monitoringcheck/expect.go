@@ -0,0 +1,152 @@
+package monitoringcheck
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Comparison operators of an Expectation
+const (
+	OpEqual        = "=="
+	OpNotEqual     = "!="
+	OpLess         = "<"
+	OpLessEqual    = "<="
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+	OpRange        = ".."
+	OpEmpty        = "empty"
+	OpNotEmpty     = "nonempty"
+)
+
+// Expectation is the parsed expected result of a Prometheus check. Supported
+// expressions are a plain number ("1", same as "== 1"), a comparison like
+// ">= 3", "< 0.05" or "!= 0", an inclusive range like "0.9..1", and "empty"
+// or "nonempty" for the size of the result set.
+type Expectation struct {
+	Op    string
+	Value float64
+	Min   float64
+	Max   float64
+	expr  string
+}
+
+// ParseExpectation parses an expected result expression
+func ParseExpectation(expr string) (Expectation, error) {
+	e := Expectation{expr: strings.TrimSpace(expr)}
+
+	switch strings.ToLower(e.expr) {
+	case "":
+		return e, fmt.Errorf("empty expectation")
+	case OpEmpty:
+		e.Op = OpEmpty
+		return e, nil
+	case OpNotEmpty, "non-empty", "not empty":
+		e.Op = OpNotEmpty
+		return e, nil
+	}
+
+	if low, high, ok := strings.Cut(e.expr, OpRange); ok {
+		min, err := parseThreshold(low)
+		if err != nil {
+			return e, fmt.Errorf("invalid range %q: %v", e.expr, err)
+		}
+		max, err := parseThreshold(high)
+		if err != nil {
+			return e, fmt.Errorf("invalid range %q: %v", e.expr, err)
+		}
+		if min > max {
+			return e, fmt.Errorf("invalid range %q: lower bound is greater than upper bound", e.expr)
+		}
+		e.Op, e.Min, e.Max = OpRange, min, max
+		return e, nil
+	}
+
+	// two character operators first, so that ">=" is not parsed as ">"
+	e.Op = OpEqual
+	threshold := e.expr
+	for _, op := range []string{OpEqual, OpNotEqual, OpLessEqual, OpGreaterEqual, OpLess, OpGreater} {
+		if strings.HasPrefix(e.expr, op) {
+			e.Op = op
+			threshold = strings.TrimPrefix(e.expr, op)
+			break
+		}
+	}
+
+	value, err := parseThreshold(threshold)
+	if err != nil {
+		return e, fmt.Errorf("invalid expectation %q: %v", e.expr, err)
+	}
+	e.Value = value
+	return e, nil
+}
+
+// parseThreshold parses a number of an expectation, NaN is not allowed
+func parseThreshold(s string) (float64, error) {
+	value, err := ParseValue(strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) {
+		return 0, fmt.Errorf("NaN can not be compared")
+	}
+	return value, nil
+}
+
+// ParseValue parses a Prometheus sample value, including "NaN", "+Inf" and "-Inf"
+func ParseValue(s string) (float64, error) {
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	return value, nil
+}
+
+// String returns the expression of the expectation
+func (e Expectation) String() string {
+	return e.expr
+}
+
+// Numeric reports whether the expectation compares sample values rather than
+// the size of the result set
+func (e Expectation) Numeric() bool {
+	return e.Op != OpEmpty && e.Op != OpNotEmpty
+}
+
+// MatchCount reports whether a result set of the given size satisfies the expectation
+func (e Expectation) MatchCount(count int) bool {
+	switch e.Op {
+	case OpEmpty:
+		return count == 0
+	case OpNotEmpty:
+		return count > 0
+	}
+	return count > 0
+}
+
+// Match reports whether a sample value satisfies the expectation. NaN never
+// satisfies a numeric expectation.
+func (e Expectation) Match(value float64) bool {
+	if math.IsNaN(value) {
+		return false
+	}
+
+	switch e.Op {
+	case OpEqual:
+		return value == e.Value
+	case OpNotEqual:
+		return value != e.Value
+	case OpLess:
+		return value < e.Value
+	case OpLessEqual:
+		return value <= e.Value
+	case OpGreater:
+		return value > e.Value
+	case OpGreaterEqual:
+		return value >= e.Value
+	case OpRange:
+		return value >= e.Min && value <= e.Max
+	}
+	return false
+}
@@ -0,0 +1,200 @@
+package monitoringcheck
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+)
+
+func TestParseExpectation(t *testing.T) {
+	tests := []struct {
+		expr    string
+		op      string
+		wantErr bool
+	}{
+		{"1", OpEqual, false},
+		{"== 0", OpEqual, false},
+		{"!=0", OpNotEqual, false},
+		{">= 3", OpGreaterEqual, false},
+		{"> 3", OpGreater, false},
+		{"<= 1e3", OpLessEqual, false},
+		{"< 0.05", OpLess, false},
+		{"< +Inf", OpLess, false},
+		{"0.9..1", OpRange, false},
+		{"-Inf .. 0", OpRange, false},
+		{"empty", OpEmpty, false},
+		{"non-empty", OpNotEmpty, false},
+		{"nonempty", OpNotEmpty, false},
+		{"", "", true},
+		{">= three", "", true},
+		{"== NaN", "", true},
+		{"1..0", "", true},
+		{"1..", "", true},
+		{"=> 3", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := ParseExpectation(tt.expr)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", e)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if e.Op != tt.op {
+				t.Errorf("Expected operator %s, got %s", tt.op, e.Op)
+			}
+		})
+	}
+}
+
+func TestExpectationMatch(t *testing.T) {
+	tests := []struct {
+		expr  string
+		value float64
+		want  bool
+	}{
+		{"1", 1, true},
+		{"1", 1.0000001, false},
+		{"== 0", 0, true},
+		{"!= 0", 2, true},
+		{"!= 0", math.NaN(), false},
+		{">= 3", 3, true},
+		{">= 3", 2, false},
+		{"> 3", 3, false},
+		{"< 0.05", 0.01, true},
+		{"< 0.05", 0.05, false},
+		{"<= 0.05", 0.05, true},
+		{"< 0.05", math.NaN(), false},
+		{"< 0.05", math.Inf(1), false},
+		{"> 100", math.Inf(1), true},
+		{"== +Inf", math.Inf(1), true},
+		{"0.9..1", 0.95, true},
+		{"0.9..1", 1, true},
+		{"0.9..1", 0.89, false},
+		{"0.9..1", math.NaN(), false},
+	}
+
+	for _, tt := range tests {
+		e, err := ParseExpectation(tt.expr)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %v", tt.expr, err)
+		}
+		if got := e.Match(tt.value); got != tt.want {
+			t.Errorf("%q matching %v: expected %v, got %v", tt.expr, tt.value, tt.want, got)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	for _, s := range []string{"1", "0.5", "NaN", "+Inf", "-Inf", "1e-3"} {
+		if _, err := ParseValue(s); err != nil {
+			t.Errorf("Failed to parse %q: %v", s, err)
+		}
+	}
+	if _, err := ParseValue("abc"); err == nil {
+		t.Error("Expected error for invalid value, got nil")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		samples []Sample
+		passed  bool
+		value   string
+		message string
+	}{
+		{"equal", "1", []Sample{{Value: "1"}}, true, "1", "Healthy"},
+		{"float equal", "1", []Sample{{Value: "1.0"}}, true, "1.0", "Healthy"},
+		{"below threshold", ">= 3", []Sample{{Value: "2"}}, false, "2", "Value: 2 (expected: >= 3)"},
+		{"error ratio", "< 0.05", []Sample{{Value: "0.0123"}}, true, "0.0123", "Healthy"},
+		{"NaN", "< 0.05", []Sample{{Value: "NaN"}}, false, "NaN", "Value: NaN (expected: < 0.05)"},
+		{"empty result", "1", []Sample{}, false, "", "Empty result (expected: 1)"},
+		{"expect empty", "empty", []Sample{}, true, "0 series", "Healthy"},
+		{"expect empty with series", "empty", []Sample{{Value: "1"}}, false, "1 series", "Value: 1 series (expected: empty)"},
+		{"expect nonempty", "nonempty", []Sample{{Value: "0"}}, true, "1 series", "Healthy"},
+		{"invalid value", "1", []Sample{{Value: "abc"}}, false, "abc", `Invalid value: "abc" is not a number`},
+		{"all series healthy", "1", []Sample{
+			{Metric: map[string]string{"node": "a"}, Value: "1"},
+			{Metric: map[string]string{"node": "b"}, Value: "1"},
+		}, true, "1", "Healthy"},
+		{"one series failed", "1", []Sample{
+			{Metric: map[string]string{"node": "a"}, Value: "1"},
+			{Metric: map[string]string{"node": "b"}, Value: "0"},
+			{Metric: map[string]string{"node": "c"}, Value: "NaN"},
+		}, false, "0", "2 of 3 series failed (expected: 1)"},
+		{"range healthy", "1", []Sample{
+			{Metric: map[string]string{"job": "apiserver"}, Value: "1", Values: []string{"1", "1", "1"}},
+		}, true, "1", "Healthy"},
+		{"range with gap", "1", []Sample{
+			{Metric: map[string]string{"job": "apiserver"}, Value: "1", Values: []string{"1", "0", "0", "1"}},
+		}, false, "0", "Value: 0, 2 of 4 samples failed (expected: 1)"},
+		{"scalar", ">= 3", []Sample{{Metric: map[string]string{}, Value: "42"}}, true, "42", "Healthy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expect, err := ParseExpectation(tt.expr)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %v", tt.expr, err)
+			}
+
+			result := evaluate(checker.Result{Name: "TEST"}, expect, tt.samples)
+			if result.Passed != tt.passed {
+				t.Errorf("Expected passed %v, got %v (%s)", tt.passed, result.Passed, result.Message)
+			}
+			if result.Value != tt.value {
+				t.Errorf("Expected value %q, got %q", tt.value, result.Value)
+			}
+			if !strings.HasPrefix(result.Message, tt.message) {
+				t.Errorf("Expected message %q, got %q", tt.message, result.Message)
+			}
+		})
+	}
+}
+
+func TestEvaluateFindings(t *testing.T) {
+	expect, _ := ParseExpectation("1")
+	result := evaluate(checker.Result{Name: "NODE"}, expect, []Sample{
+		{Metric: map[string]string{"__name__": "kube_node_status_condition", "node": "node-1", "condition": "Ready"}, Value: "1"},
+		{Metric: map[string]string{"__name__": "kube_node_status_condition", "node": "node-2", "condition": "Ready"}, Value: "0"},
+	})
+
+	if len(result.Findings) != 2 {
+		t.Fatalf("Expected one finding per series, got %d", len(result.Findings))
+	}
+
+	failed := result.Failed()
+	if len(failed) != 1 {
+		t.Fatalf("Expected 1 failed series, got %d", len(failed))
+	}
+	if failed[0].Name != `kube_node_status_condition{condition="Ready",node="node-2"}` {
+		t.Errorf("Unexpected series name: %s", failed[0].Name)
+	}
+	if failed[0].Labels["node"] != "node-2" || failed[0].Status != "0" {
+		t.Errorf("Unexpected finding: %+v", failed[0])
+	}
+
+	// aggregated series without labels are not reported as findings
+	result = evaluate(checker.Result{Name: "NODE"}, expect, []Sample{{Metric: map[string]string{}, Value: "0"}})
+	if len(result.Findings) != 0 {
+		t.Errorf("Expected no findings for a series without labels, got %+v", result.Findings)
+	}
+
+	// series of a result expected to be empty are unhealthy
+	expect, _ = ParseExpectation("empty")
+	result = evaluate(checker.Result{Name: "ALERTS"}, expect, []Sample{
+		{Metric: map[string]string{"alertname": "KubePodCrashLooping"}, Value: "1"},
+	})
+	if result.Passed || len(result.Failed()) != 1 || result.Failed()[0].Name != `{alertname="KubePodCrashLooping"}` {
+		t.Errorf("Expected firing alert to be reported, got %+v", result)
+	}
+}
@@ -0,0 +1,201 @@
+package monitoringcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"github.com/eumel8/clustercheck/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// PortForward locates the Prometheus service like ServiceProxy and opens a
+// tunnel from an ephemeral local port to a ready pod of the service. It
+// returns the local Prometheus URL, the HTTP client of the tunnel with the
+// given TLS options and a function closing the tunnel.
+func PortForward(ctx context.Context, kube checker.KubeOptions, ref string, selector string, tlsOpts checker.TLSOptions, retry checker.Retry, debug bool) (string, *http.Client, func(), error) {
+	config, clientset, err := kubeClient(kube)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	service, err := findService(ctx, clientset, ref, selector, retry)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	port, err := servicePort(service.Spec.Ports, service.Port)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("service %s: %v", service, err)
+	}
+
+	httpClient, err := NewHTTPClient(forwardTLS(tlsOpts, service))
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	pod, err := findPod(ctx, clientset, service, retry)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	targetPort, err := podPort(pod, port)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	}
+
+	portForwardURL := clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(pod.Namespace).Name(pod.Name).SubResource("portforward").URL()
+
+	// tunnel over websockets like kubectl, falling back to SPDY for older API servers
+	transport, upgrader, err := spdy.RoundTripperFor(config)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to create port-forward transport: %v", err)
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, portForwardURL)
+	tunnelingDialer, err := portforward.NewSPDYOverWebsocketDialer(portForwardURL, config)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to create port-forward dialer: %v", err)
+	}
+	dialer = portforward.NewFallbackDialer(tunnelingDialer, dialer, func(err error) bool {
+		return httpstream.IsUpgradeFailure(err) || httpstream.IsHTTPSProxyError(err)
+	})
+
+	errOut := io.Discard
+	if debug {
+		errOut = os.Stderr
+	}
+	stop := make(chan struct{})
+	ready := make(chan struct{})
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"},
+		[]string{fmt.Sprintf("0:%d", targetPort)}, stop, ready, io.Discard, errOut)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("failed to create port-forward: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- forwarder.ForwardPorts()
+	}()
+	closeTunnel := func() {
+		close(stop)
+		<-done
+	}
+
+	select {
+	case <-ready:
+	case err := <-done:
+		return "", nil, nil, fmt.Errorf("failed to port-forward to pod %s/%s: %v", pod.Namespace, pod.Name, err)
+	case <-ctx.Done():
+		closeTunnel()
+		return "", nil, nil, fmt.Errorf("failed to port-forward to pod %s/%s: %v", pod.Namespace, pod.Name, ctx.Err())
+	}
+
+	ports, err := forwarder.GetPorts()
+	if err != nil || len(ports) == 0 {
+		closeTunnel()
+		return "", nil, nil, fmt.Errorf("failed to get the local port of the port-forward: %v", err)
+	}
+
+	scheme := "http"
+	if strings.Contains(port.Name, "https") || port.Port == 443 {
+		scheme = "https"
+	}
+	localURL := fmt.Sprintf("%s://127.0.0.1:%d", scheme, ports[0].Local)
+
+	if debug {
+		fmt.Fprintf(os.Stderr, "\n[DEBUG] Prometheus Port Forward:\n")
+		fmt.Fprintf(os.Stderr, "  Kubeconfig: %s\n", strings.Join(common.KubeConfigFiles(kube), string(filepath.ListSeparator)))
+		fmt.Fprintf(os.Stderr, "  Service: %s/%s:%s\n", service.Namespace, service.Name, portName(port))
+		fmt.Fprintf(os.Stderr, "  Pod: %s/%s:%d\n", pod.Namespace, pod.Name, targetPort)
+		fmt.Fprintf(os.Stderr, "  URL: %s\n", localURL)
+	}
+
+	return localURL, httpClient, closeTunnel, nil
+}
+
+// forwardTLS returns the TLS options of a port-forward to the service. The
+// local address is not in the certificate of the service, so it is verified
+// against the service DNS name <service>.<namespace>.svc if no server name is
+// given.
+func forwardTLS(opts checker.TLSOptions, service prometheusService) checker.TLSOptions {
+	if opts.ServerName == "" {
+		opts.ServerName = service.Name + "." + service.Namespace + ".svc"
+	}
+	return opts
+}
+
+// findPod returns the first ready pod of the service by name
+func findPod(ctx context.Context, clientset kubernetes.Interface, service prometheusService, retry checker.Retry) (corev1.Pod, error) {
+	if len(service.Spec.Selector) == 0 {
+		return corev1.Pod{}, fmt.Errorf("service %s has no pod selector", service)
+	}
+	selector := labels.SelectorFromSet(service.Spec.Selector).String()
+
+	var pods *corev1.PodList
+	_, err := retry.Do(ctx, common.IsRetryableAPIError, func() error {
+		var err error
+		pods, err = clientset.CoreV1().Pods(service.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		return err
+	})
+	if err != nil {
+		return corev1.Pod{}, fmt.Errorf("failed to list Prometheus pods: %v", err)
+	}
+
+	ready := []corev1.Pod{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil && podReady(pod) {
+			ready = append(ready, pod)
+		}
+	}
+	if len(ready) == 0 {
+		return corev1.Pod{}, fmt.Errorf("no ready Prometheus pod found for service %s", service)
+	}
+
+	sort.Slice(ready, func(i, j int) bool {
+		return ready[i].Name < ready[j].Name
+	})
+	return ready[0], nil
+}
+
+// podReady reports whether the Ready condition of the pod is true
+func podReady(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podPort returns the container port of the pod the service port targets
+func podPort(pod corev1.Pod, port corev1.ServicePort) (int32, error) {
+	switch {
+	case port.TargetPort.Type == intstr.String && port.TargetPort.StrVal != "":
+		for _, container := range pod.Spec.Containers {
+			for _, containerPort := range container.Ports {
+				if containerPort.Name == port.TargetPort.StrVal {
+					return containerPort.ContainerPort, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("no container port %s", port.TargetPort.StrVal)
+	case port.TargetPort.IntVal != 0:
+		return port.TargetPort.IntVal, nil
+	default:
+		return port.Port, nil
+	}
+}
@@ -0,0 +1,99 @@
+package monitoringcheck
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func prometheusPod(name string, phase corev1.PodPhase, ready corev1.ConditionStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: name, Labels: map[string]string{"app.kubernetes.io/name": "prometheus"}},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "prometheus",
+			Ports: []corev1.ContainerPort{{Name: "http-web", ContainerPort: 9090}},
+		}}},
+		Status: corev1.PodStatus{
+			Phase:      phase,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+		},
+	}
+}
+
+func TestFindPod(t *testing.T) {
+	clientset := fake.NewClientset(
+		prometheusPod("prometheus-0", corev1.PodPending, corev1.ConditionFalse),
+		prometheusPod("prometheus-1", corev1.PodRunning, corev1.ConditionFalse),
+		prometheusPod("prometheus-2", corev1.PodRunning, corev1.ConditionTrue),
+		prometheusPod("prometheus-3", corev1.PodRunning, corev1.ConditionTrue),
+	)
+
+	service := prometheusService{Service: corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "prometheus"},
+		Spec:       corev1.ServiceSpec{Selector: map[string]string{"app.kubernetes.io/name": "prometheus"}},
+	}}
+	pod, err := findPod(context.Background(), clientset, service, checker.Retry{})
+	if err != nil || pod.Name != "prometheus-2" {
+		t.Errorf("Expected the first ready pod prometheus-2, got %s (%v)", pod.Name, err)
+	}
+
+	service.Spec.Selector = map[string]string{"app.kubernetes.io/name": "thanos"}
+	if _, err := findPod(context.Background(), clientset, service, checker.Retry{}); err == nil || !strings.Contains(err.Error(), "no ready Prometheus pod") {
+		t.Errorf("Expected error for service without ready pods, got: %v", err)
+	}
+
+	service.Spec.Selector = nil
+	if _, err := findPod(context.Background(), clientset, service, checker.Retry{}); err == nil || !strings.Contains(err.Error(), "no pod selector") {
+		t.Errorf("Expected error for service without selector, got: %v", err)
+	}
+}
+
+func TestPodPort(t *testing.T) {
+	pod := *prometheusPod("prometheus-0", corev1.PodRunning, corev1.ConditionTrue)
+
+	tests := []struct {
+		name    string
+		port    corev1.ServicePort
+		want    int32
+		wantErr bool
+	}{
+		{"named target port", corev1.ServicePort{Port: 80, TargetPort: intstr.FromString("http-web")}, 9090, false},
+		{"numbered target port", corev1.ServicePort{Port: 80, TargetPort: intstr.FromInt32(9091)}, 9091, false},
+		{"no target port", corev1.ServicePort{Port: 9090}, 9090, false},
+		{"unknown target port", corev1.ServicePort{Port: 80, TargetPort: intstr.FromString("grpc")}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, err := podPort(pod, tt.port)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %d", port)
+				}
+				return
+			}
+			if err != nil || port != tt.want {
+				t.Errorf("Expected port %d, got %d (%v)", tt.want, port, err)
+			}
+		})
+	}
+}
+
+func TestForwardTLS(t *testing.T) {
+	service := prometheusService{Service: corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "prometheus"},
+	}}
+
+	if opts := forwardTLS(checker.TLSOptions{CAFile: "ca.pem"}, service); opts.ServerName != "prometheus.monitoring.svc" || opts.CAFile != "ca.pem" {
+		t.Errorf("Expected server name prometheus.monitoring.svc, got %+v", opts)
+	}
+	if opts := forwardTLS(checker.TLSOptions{ServerName: "prometheus.example.com"}, service); opts.ServerName != "prometheus.example.com" {
+		t.Errorf("Expected the configured server name prometheus.example.com, got %+v", opts)
+	}
+}
@@ -0,0 +1,121 @@
+package monitoringcheck
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Result types of the Prometheus query API
+const (
+	ResultTypeVector = "vector"
+	ResultTypeMatrix = "matrix"
+	ResultTypeScalar = "scalar"
+	ResultTypeString = "string"
+)
+
+// Sample is a single series of a Prometheus query result. Value is the value
+// of an instant query, or the last value of a range query series whose values
+// are in Values.
+type Sample struct {
+	Metric map[string]string
+	Value  string
+	Values []string
+}
+
+// queryResponse is the envelope of the Prometheus query API responses
+type queryResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+// parseResponse parses the body of a query or query_range response into samples.
+// Scalar and string results are returned as a single sample without labels.
+func parseResponse(body []byte) ([]Sample, error) {
+	var response queryResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, err
+	}
+
+	// an error response would otherwise look like an empty result
+	if response.Status == "error" {
+		return nil, fmt.Errorf("query failed: %s", response.Error)
+	}
+
+	samples := []Sample{}
+	if len(response.Data.Result) == 0 {
+		return samples, nil
+	}
+
+	switch response.Data.ResultType {
+	case ResultTypeVector, "":
+		var vector []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+		}
+		if err := json.Unmarshal(response.Data.Result, &vector); err != nil {
+			return nil, err
+		}
+		for _, series := range vector {
+			value, err := sampleValue(series.Value)
+			if err != nil {
+				return nil, err
+			}
+			samples = append(samples, Sample{Metric: series.Metric, Value: value})
+		}
+
+	case ResultTypeMatrix:
+		var matrix []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][]interface{}   `json:"values"`
+		}
+		if err := json.Unmarshal(response.Data.Result, &matrix); err != nil {
+			return nil, err
+		}
+		for _, series := range matrix {
+			sample := Sample{Metric: series.Metric, Values: []string{}}
+			for _, pair := range series.Values {
+				value, err := sampleValue(pair)
+				if err != nil {
+					return nil, err
+				}
+				sample.Values = append(sample.Values, value)
+			}
+			if len(sample.Values) > 0 {
+				sample.Value = sample.Values[len(sample.Values)-1]
+			}
+			samples = append(samples, sample)
+		}
+
+	case ResultTypeScalar, ResultTypeString:
+		var pair []interface{}
+		if err := json.Unmarshal(response.Data.Result, &pair); err != nil {
+			return nil, err
+		}
+		value, err := sampleValue(pair)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, Sample{Metric: map[string]string{}, Value: value})
+
+	default:
+		return nil, fmt.Errorf("unsupported result type %q", response.Data.ResultType)
+	}
+
+	return samples, nil
+}
+
+// sampleValue returns the value of a [timestamp, "value"] pair
+func sampleValue(pair []interface{}) (string, error) {
+	if len(pair) != 2 {
+		return "", fmt.Errorf("unexpected sample %v", pair)
+	}
+	value, ok := pair[1].(string)
+	if !ok {
+		return "", fmt.Errorf("unexpected sample value %v", pair[1])
+	}
+	return value, nil
+}
@@ -0,0 +1,95 @@
+package monitoringcheck
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseResponse(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		samples []Sample
+		errText string
+	}{
+		{
+			name: "vector",
+			body: `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"node":"a"},"value":[1700000000.1,"1"]},
+				{"metric":{"node":"b"},"value":[1700000000.1,"0"]}]}}`,
+			samples: []Sample{
+				{Metric: map[string]string{"node": "a"}, Value: "1"},
+				{Metric: map[string]string{"node": "b"}, Value: "0"},
+			},
+		},
+		{
+			name: "matrix",
+			body: `{"status":"success","data":{"resultType":"matrix","result":[
+				{"metric":{"job":"apiserver"},"values":[[1700000000,"1"],[1700000060,"0"],[1700000120,"1"]]}]}}`,
+			samples: []Sample{
+				{Metric: map[string]string{"job": "apiserver"}, Value: "1", Values: []string{"1", "0", "1"}},
+			},
+		},
+		{
+			name:    "scalar",
+			body:    `{"status":"success","data":{"resultType":"scalar","result":[1700000000,"42"]}}`,
+			samples: []Sample{{Metric: map[string]string{}, Value: "42"}},
+		},
+		{
+			name:    "string",
+			body:    `{"status":"success","data":{"resultType":"string","result":[1700000000,"hello"]}}`,
+			samples: []Sample{{Metric: map[string]string{}, Value: "hello"}},
+		},
+		{
+			name:    "empty vector",
+			body:    `{"status":"success","data":{"resultType":"vector","result":[]}}`,
+			samples: []Sample{},
+		},
+		{
+			name:    "error response",
+			body:    `{"status":"error","errorType":"bad_data","error":"invalid parameter \"query\""}`,
+			errText: "invalid parameter",
+		},
+		{
+			name:    "unsupported result type",
+			body:    `{"status":"success","data":{"resultType":"histogram","result":[1]}}`,
+			errText: "unsupported result type",
+		},
+		{
+			name:    "unexpected sample value",
+			body:    `{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1700000000,1]}]}}`,
+			errText: "unexpected sample value",
+		},
+		{
+			name:    "invalid JSON",
+			body:    `{"status":`,
+			errText: "unexpected end of JSON input",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			samples, err := parseResponse([]byte(tt.body))
+			if tt.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.errText, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(samples) != len(tt.samples) {
+				t.Fatalf("Expected %d samples, got %d", len(tt.samples), len(samples))
+			}
+			for i, sample := range samples {
+				want := tt.samples[i]
+				if sample.Value != want.Value || seriesName(sample.Metric) != seriesName(want.Metric) ||
+					strings.Join(sample.Values, ",") != strings.Join(want.Values, ",") {
+					t.Errorf("Sample %d: expected %+v, got %+v", i, want, sample)
+				}
+			}
+		})
+	}
+}
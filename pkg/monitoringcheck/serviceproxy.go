@@ -0,0 +1,226 @@
+package monitoringcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"github.com/eumel8/clustercheck/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultPrometheusSelector discovers the Prometheus service of the Prometheus
+// operator and the Prometheus Helm chart
+const DefaultPrometheusSelector = "app.kubernetes.io/name=prometheus"
+
+// preferredPorts are the names of Prometheus service ports in the order of preference
+var preferredPorts = []string{"web", "http-web", "http", "https"}
+
+// ServiceRef references a service port as "namespace/name[:port]". The port is
+// a name or number.
+type ServiceRef struct {
+	Namespace string
+	Name      string
+	Port      string
+}
+
+// ParseServiceRef parses a "namespace/name[:port]" service reference
+func ParseServiceRef(ref string) (ServiceRef, error) {
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok || namespace == "" || name == "" {
+		return ServiceRef{}, fmt.Errorf("invalid service %q, expected namespace/name[:port]", ref)
+	}
+	service := ServiceRef{Namespace: namespace, Name: name}
+	if name, port, ok := strings.Cut(name, ":"); ok {
+		if name == "" || port == "" {
+			return ServiceRef{}, fmt.Errorf("invalid service %q, expected namespace/name[:port]", ref)
+		}
+		service.Name = name
+		service.Port = port
+	}
+	return service, nil
+}
+
+// String returns the service reference as "namespace/name[:port]"
+func (s ServiceRef) String() string {
+	if s.Port == "" {
+		return s.Namespace + "/" + s.Name
+	}
+	return s.Namespace + "/" + s.Name + ":" + s.Port
+}
+
+// ServiceProxy locates the Prometheus service and returns the URL of its API
+// server service proxy and an HTTP client authenticated with the kubeconfig
+// credentials. The service is looked up by ref, or discovered in all
+// namespaces by the label selector if ref is empty.
+func ServiceProxy(ctx context.Context, kube checker.KubeOptions, ref string, selector string, retry checker.Retry, debug bool) (string, *http.Client, error) {
+	config, clientset, err := kubeClient(kube)
+	if err != nil {
+		return "", nil, err
+	}
+
+	service, err := findService(ctx, clientset, ref, selector, retry)
+	if err != nil {
+		return "", nil, err
+	}
+
+	port, err := servicePort(service.Spec.Ports, service.Port)
+	if err != nil {
+		return "", nil, fmt.Errorf("service %s: %v", service, err)
+	}
+
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create HTTP client: %v", err)
+	}
+
+	proxyURL := serviceProxyURL(config.Host, service.Namespace, service.Name, port)
+	if debug {
+		fmt.Fprintf(os.Stderr, "\n[DEBUG] Prometheus Service Proxy:\n")
+		fmt.Fprintf(os.Stderr, "  Kubeconfig: %s\n", strings.Join(common.KubeConfigFiles(kube), string(filepath.ListSeparator)))
+		fmt.Fprintf(os.Stderr, "  Service: %s/%s:%s\n", service.Namespace, service.Name, portName(port))
+		fmt.Fprintf(os.Stderr, "  URL: %s\n", proxyURL)
+	}
+
+	return proxyURL, httpClient, nil
+}
+
+// kubeClient creates a clientset from the kubeconfig selected by kube
+func kubeClient(kube checker.KubeOptions) (*rest.Config, kubernetes.Interface, error) {
+	config, err := common.RESTConfig(kube)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build config: %v", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create clientset: %v", err)
+	}
+	return config, clientset, nil
+}
+
+// prometheusService is a located Prometheus service with the requested port
+type prometheusService struct {
+	corev1.Service
+	Port string
+}
+
+// String returns the namespaced name of the service
+func (s prometheusService) String() string {
+	return s.Namespace + "/" + s.Name
+}
+
+// findService gets the referenced service, or discovers the service by the
+// label selector. Of several matching services the first by namespace and
+// name is returned.
+func findService(ctx context.Context, clientset kubernetes.Interface, ref string, selector string, retry checker.Retry) (prometheusService, error) {
+	if ref != "" {
+		serviceRef, err := ParseServiceRef(ref)
+		if err != nil {
+			return prometheusService{}, err
+		}
+
+		var service *corev1.Service
+		_, err = retry.Do(ctx, common.IsRetryableAPIError, func() error {
+			var err error
+			service, err = clientset.CoreV1().Services(serviceRef.Namespace).Get(ctx, serviceRef.Name, metav1.GetOptions{})
+			return err
+		})
+		if err != nil {
+			return prometheusService{}, fmt.Errorf("failed to get Prometheus service %s: %v", serviceRef, err)
+		}
+		return prometheusService{Service: *service, Port: serviceRef.Port}, nil
+	}
+
+	if selector == "" {
+		selector = DefaultPrometheusSelector
+	}
+
+	var services *corev1.ServiceList
+	_, err := retry.Do(ctx, common.IsRetryableAPIError, func() error {
+		var err error
+		services, err = clientset.CoreV1().Services("").List(ctx, metav1.ListOptions{LabelSelector: selector})
+		return err
+	})
+	if err != nil {
+		return prometheusService{}, fmt.Errorf("failed to list Prometheus services: %v", err)
+	}
+
+	candidates := []corev1.Service{}
+	for _, service := range services.Items {
+		// the headless service of the Prometheus operator has no cluster IP to proxy to
+		if service.Spec.ClusterIP != corev1.ClusterIPNone {
+			candidates = append(candidates, service)
+		}
+	}
+	if len(candidates) == 0 {
+		return prometheusService{}, fmt.Errorf("no Prometheus service found with selector %q", selector)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Namespace != candidates[j].Namespace {
+			return candidates[i].Namespace < candidates[j].Namespace
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	return prometheusService{Service: candidates[0]}, nil
+}
+
+// servicePort returns the port of the given name or number. Without port the
+// first preferred port name, port 9090 or the first port is returned.
+func servicePort(ports []corev1.ServicePort, port string) (corev1.ServicePort, error) {
+	if len(ports) == 0 {
+		return corev1.ServicePort{}, fmt.Errorf("no ports")
+	}
+
+	if port != "" {
+		for _, p := range ports {
+			if p.Name == port || strconv.Itoa(int(p.Port)) == port {
+				return p, nil
+			}
+		}
+		return corev1.ServicePort{}, fmt.Errorf("no port %s", port)
+	}
+
+	for _, name := range preferredPorts {
+		for _, p := range ports {
+			if p.Name == name {
+				return p, nil
+			}
+		}
+	}
+	for _, p := range ports {
+		if p.Port == 9090 {
+			return p, nil
+		}
+	}
+	return ports[0], nil
+}
+
+// serviceProxyURL returns the URL of the API server proxy to a service port.
+// Ports named https are proxied with TLS.
+func serviceProxyURL(host string, namespace string, name string, port corev1.ServicePort) string {
+	scheme := ""
+	if strings.Contains(port.Name, "https") || port.Port == 443 {
+		scheme = "https:"
+	}
+	return fmt.Sprintf("%s/api/v1/namespaces/%s/services/%s%s:%s/proxy",
+		strings.TrimSuffix(host, "/"), namespace, scheme, name, portName(port))
+}
+
+// portName returns the name of the port, or its number if it has no name
+func portName(port corev1.ServicePort) string {
+	if port.Name != "" {
+		return port.Name
+	}
+	return strconv.Itoa(int(port.Port))
+}
@@ -0,0 +1,226 @@
+package monitoringcheck
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestParseServiceRef(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    ServiceRef
+		wantErr bool
+	}{
+		{"monitoring/prometheus", ServiceRef{Namespace: "monitoring", Name: "prometheus"}, false},
+		{"monitoring/prometheus:web", ServiceRef{Namespace: "monitoring", Name: "prometheus", Port: "web"}, false},
+		{"monitoring/prometheus:9090", ServiceRef{Namespace: "monitoring", Name: "prometheus", Port: "9090"}, false},
+		{"prometheus", ServiceRef{}, true},
+		{"/prometheus", ServiceRef{}, true},
+		{"monitoring/", ServiceRef{}, true},
+		{"monitoring/prometheus:", ServiceRef{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseServiceRef(tt.ref)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected error, got %+v", tt.ref, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: expected %+v, got %+v (%v)", tt.ref, tt.want, got, err)
+		}
+		if got.String() != tt.ref {
+			t.Errorf("Expected %q, got %q", tt.ref, got.String())
+		}
+	}
+}
+
+func TestServicePort(t *testing.T) {
+	tests := []struct {
+		name    string
+		ports   []corev1.ServicePort
+		port    string
+		want    string
+		wantErr bool
+	}{
+		{"preferred name", []corev1.ServicePort{{Name: "reloader-web", Port: 8080}, {Name: "http-web", Port: 9090}}, "", "http-web", false},
+		{"port 9090", []corev1.ServicePort{{Name: "metrics", Port: 8080}, {Name: "api", Port: 9090}}, "", "api", false},
+		{"first port", []corev1.ServicePort{{Port: 8080}}, "", "8080", false},
+		{"by name", []corev1.ServicePort{{Name: "web", Port: 9090}, {Name: "grpc", Port: 10901}}, "grpc", "grpc", false},
+		{"by number", []corev1.ServicePort{{Name: "web", Port: 9090}, {Name: "grpc", Port: 10901}}, "10901", "grpc", false},
+		{"unknown port", []corev1.ServicePort{{Name: "web", Port: 9090}}, "grpc", "", true},
+		{"no ports", nil, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, err := servicePort(tt.ports, tt.port)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", port)
+				}
+				return
+			}
+			if err != nil || portName(port) != tt.want {
+				t.Errorf("Expected port %s, got %s (%v)", tt.want, portName(port), err)
+			}
+		})
+	}
+}
+
+func TestServiceProxyURL(t *testing.T) {
+	url := serviceProxyURL("https://rancher.example.com/k8s/clusters/c-1/", "monitoring", "prometheus", corev1.ServicePort{Name: "web", Port: 9090})
+	if url != "https://rancher.example.com/k8s/clusters/c-1/api/v1/namespaces/monitoring/services/prometheus:web/proxy" {
+		t.Errorf("Unexpected proxy URL: %s", url)
+	}
+
+	url = serviceProxyURL("https://api.example.com:6443", "monitoring", "thanos", corev1.ServicePort{Name: "https", Port: 10902})
+	if url != "https://api.example.com:6443/api/v1/namespaces/monitoring/services/https:thanos:https/proxy" {
+		t.Errorf("Unexpected proxy URL: %s", url)
+	}
+}
+
+func TestFindService(t *testing.T) {
+	labels := map[string]string{"app.kubernetes.io/name": "prometheus"}
+	clientset := fake.NewClientset(
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "prometheus-operated", Labels: labels},
+			Spec:       corev1.ServiceSpec{ClusterIP: corev1.ClusterIPNone},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "prometheus", Labels: labels},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.0.0.1"},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "prometheus", Labels: labels},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.0.0.2"},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "thanos", Name: "query", Labels: map[string]string{"app": "thanos-query"}},
+			Spec:       corev1.ServiceSpec{ClusterIP: "10.0.0.3"},
+		},
+	)
+
+	tests := []struct {
+		name     string
+		ref      string
+		selector string
+		want     string
+		errText  string
+	}{
+		{"default selector", "", "", "monitoring/prometheus", ""},
+		{"selector", "", "app=thanos-query", "thanos/query", ""},
+		{"reference", "team-a/prometheus:web", "", "team-a/prometheus", ""},
+		{"no match", "", "app=victoria-metrics", "", "no Prometheus service found"},
+		{"not found", "monitoring/thanos", "", "", "failed to get Prometheus service"},
+		{"invalid reference", "prometheus", "", "", "invalid service"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, err := findService(context.Background(), clientset, tt.ref, tt.selector, checker.Retry{})
+			if tt.errText != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errText) {
+					t.Errorf("Expected error containing %q, got: %v", tt.errText, err)
+				}
+				return
+			}
+			if err != nil || service.String() != tt.want {
+				t.Errorf("Expected service %s, got %s (%v)", tt.want, service, err)
+			}
+		})
+	}
+}
+
+func TestCheckerRunServiceProxy(t *testing.T) {
+	service := corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "prometheus"},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.0.0.1",
+			Ports:     []corev1.ServicePort{{Name: "reloader-web", Port: 8080}, {Name: "web", Port: 9090}},
+		},
+	}
+
+	// the API server serves the service and proxies the queries
+	var proxied, tenants []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer kube-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/namespaces/monitoring/services/prometheus":
+			json.NewEncoder(w).Encode(service)
+		case r.URL.Path == "/api/v1/namespaces/monitoring/services/prometheus:web/proxy/api/v1/query":
+			proxied = append(proxied, r.URL.Query().Get("query"))
+			tenants = append(tenants, r.Header.Get(TenantHeader))
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[0,"1"]}]}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404}`))
+		}
+	}))
+	defer server.Close()
+
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters["test"] = &clientcmdapi.Cluster{Server: server.URL, InsecureSkipTLSVerify: true}
+	kubeconfig.AuthInfos["test"] = &clientcmdapi.AuthInfo{Token: "kube-token"}
+	kubeconfig.Contexts["test"] = &clientcmdapi.Context{Cluster: "test", AuthInfo: "test"}
+	kubeconfig.CurrentContext = "test"
+	kubeconfigPath := filepath.Join(t.TempDir(), "config")
+	if err := clientcmd.WriteToFile(*kubeconfig, kubeconfigPath); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+
+	t.Setenv("KUBECONFIG", kubeconfigPath)
+	t.Setenv("CLUSTER", "test-cluster")
+	t.Setenv("CLUSTERCHECK_BW", "")
+	t.Setenv("PROM_USER", "ignored")
+
+	config := writeConfig(t, "replaceDefaults: true\nchecks:\n- name: UP\n  query: up\n")
+	results := NewChecker(checker.Options{ConfigFile: config, ServiceProxy: true, PrometheusService: "monitoring/prometheus"}).Run(context.Background())
+	if len(results) != 1 || !results[0].Passed {
+		t.Fatalf("Expected UP to pass through the service proxy, got %+v", results)
+	}
+	if len(proxied) != 1 || proxied[0] != "up" {
+		t.Errorf("Expected the query to be proxied, got %v", proxied)
+	}
+
+	results = NewChecker(checker.Options{ConfigFile: config, ServiceProxy: true, PrometheusService: "monitoring/thanos"}).Run(context.Background())
+	if len(results) != 1 || results[0].Name != "Prometheus Service Proxy" || results[0].Passed {
+		t.Errorf("Expected service proxy error, got %+v", results)
+	}
+
+	results = NewChecker(checker.Options{ConfigFile: config, PrometheusService: "monitoring/thanos", PortForward: true}).Run(context.Background())
+	if len(results) != 1 || results[0].Name != "Prometheus Port Forward" || results[0].Passed {
+		t.Errorf("Expected port-forward error, got %+v", results)
+	}
+
+	// the tenant of the configured endpoint is sent through the service proxy
+	config = writeConfig(t, "replaceDefaults: true\nendpoints:\n- url: https://mimir.example.com\n  tenant: \"{{.ShortCluster}}\"\nchecks:\n- name: UP\n  query: up\n")
+	results = NewChecker(checker.Options{ConfigFile: config, ServiceProxy: true, PrometheusService: "monitoring/prometheus", ShortClusterLabel: "test"}).Run(context.Background())
+	if len(results) != 1 || !results[0].Passed || tenants[len(tenants)-1] != "test" {
+		t.Errorf("Expected UP to pass with the tenant of the configured endpoint, got %+v and tenants %v", results, tenants)
+	}
+
+	// the service alone does not switch to the service proxy
+	if c := NewChecker(checker.Options{ConfigFile: config, PrometheusService: "monitoring/prometheus"}).(*Checker); c.serviceProxy || c.portForward {
+		t.Error("Expected the service proxy only with ServiceProxy")
+	}
+}
@@ -3,94 +3,175 @@ package podcheck
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/eumel8/clustercheck/pkg/checker"
 	"github.com/eumel8/clustercheck/pkg/common"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
-// CheckPods checks if all pods in the cluster are in Running or Succeeded state
-func CheckPods(namespace string, debug bool) error {
-	kubeconfigPath := common.GetKubeConfig()
+func init() {
+	checker.Register("pods", 10, NewChecker)
+}
+
+// Checker runs the pod health check as a registered checker
+type Checker struct {
+	namespace string
+	debug     bool
+	timeout   time.Duration
+	retry     checker.Retry
+	kube      checker.KubeOptions
+}
+
+// NewChecker creates a pod health Checker
+func NewChecker(opts checker.Options) checker.Checker {
+	return &Checker{namespace: opts.Namespace, debug: opts.Debug, timeout: opts.Timeout(), retry: opts.Retry(), kube: opts.Kube}
+}
+
+// Name returns the display name of the check
+func (c *Checker) Name() string {
+	return "Pod Health"
+}
+
+// Category returns the check category
+func (c *Checker) Category() string {
+	return checker.CategoryPods
+}
+
+// Run checks all pods and returns a single result
+func (c *Checker) Run(ctx context.Context) []checker.Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	result, err := CheckPods(ctx, c.kube, c.namespace, c.retry, c.debug)
+	if err != nil {
+		result.Passed = false
+		result.TimedOut = checker.IsTimeout(ctx, err)
+		result.Message = err.Error()
+		if result.Attempts > 1 {
+			result.Message = fmt.Sprintf("%v (%d attempts)", err, result.Attempts)
+		}
+	}
+	return []checker.Result{result}
+}
+
+// CheckPods checks if all pods in the cluster are in Running or Succeeded state.
+// Transient API errors are retried. The returned error is only set if the pods
+// could not be listed.
+func CheckPods(ctx context.Context, kube checker.KubeOptions, namespace string, retry checker.Retry, debug bool) (checker.Result, error) {
+	result := checker.Result{
+		Name:     "Pod Health",
+		Category: checker.CategoryPods,
+		Target:   namespace,
+		Findings: []checker.Finding{},
+	}
 
 	if debug {
-		fmt.Printf("\n[DEBUG] Kubernetes API Request:\n")
-		fmt.Printf("  Kubeconfig: %s\n", kubeconfigPath)
+		fmt.Fprintf(os.Stderr, "\n[DEBUG] Kubernetes API Request:\n")
+		fmt.Fprintf(os.Stderr, "  Kubeconfig: %s\n", strings.Join(common.KubeConfigFiles(kube), string(filepath.ListSeparator)))
 	}
 
-	// Build config from kubeconfig file
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	// Build config from the kubeconfig loading rules
+	config, err := common.RESTConfig(kube)
 	if err != nil {
-		return fmt.Errorf("failed to build config: %v", err)
+		return result, fmt.Errorf("failed to build config: %v", err)
 	}
 
 	if debug {
-		fmt.Printf("  API Server: %s\n", config.Host)
+		fmt.Fprintf(os.Stderr, "  API Server: %s\n", config.Host)
 	}
 
 	// Create clientset
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
-		return fmt.Errorf("failed to create clientset: %v", err)
-	}
-
-	// Get current context for display
-	currentContext, err := common.GetCurrentContext()
-	if err != nil {
-		currentContext = "unknown"
+		return result, fmt.Errorf("failed to create clientset: %v", err)
 	}
 
-	fmt.Printf("\033[36mpodcheck \033[0m on %s\n", currentContext)
-
 	// List pods
-	ctx := context.Background()
 	listOptions := metav1.ListOptions{}
 
 	if debug {
 		if namespace == "" {
-			fmt.Printf("  Operation: List Pods (all namespaces)\n")
+			fmt.Fprintf(os.Stderr, "  Operation: List Pods (all namespaces)\n")
 		} else {
-			fmt.Printf("  Operation: List Pods (namespace: %s)\n", namespace)
+			fmt.Fprintf(os.Stderr, "  Operation: List Pods (namespace: %s)\n", namespace)
 		}
 	}
 
-	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, listOptions)
+	var pods *corev1.PodList
+	result.Attempts, err = retry.Do(ctx, common.IsRetryableAPIError, func() error {
+		var err error
+		pods, err = clientset.CoreV1().Pods(namespace).List(ctx, listOptions)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("failed to list pods: %v", err)
+		return result, fmt.Errorf("failed to list pods: %v", err)
 	}
 
-	totalPods := len(pods.Items)
-
 	if debug {
-		fmt.Printf("[DEBUG] Kubernetes API Response:\n")
-		fmt.Printf("  Total Pods: %d\n\n", totalPods)
+		fmt.Fprintf(os.Stderr, "[DEBUG] Kubernetes API Response:\n")
+		fmt.Fprintf(os.Stderr, "  Total Pods: %d\n\n", len(pods.Items))
 	}
-	runningOrSucceeded := 0
-	failedPods := []string{}
 
+	failedPods := 0
 	for _, pod := range pods.Items {
-		phase := string(pod.Status.Phase)
-		podName := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
-
-		if phase == "Running" || phase == "Succeeded" {
-			runningOrSucceeded++
-			fmt.Printf("%s \033[32m🟢 %s\033[0m\n", podName, phase)
-		} else {
-			failedPods = append(failedPods, fmt.Sprintf("%s (%s)", podName, phase))
-			fmt.Printf("%s \033[31m🔴 %s\033[0m\n", podName, phase)
+		finding := podFinding(pod)
+		if !finding.Healthy {
+			failedPods++
 		}
+		result.Findings = append(result.Findings, finding)
 	}
 
-	fmt.Printf("\nSummary: %d/%d pods in Running or Succeeded state\n", runningOrSucceeded, totalPods)
+	if failedPods > 0 {
+		result.Message = fmt.Sprintf("%d pods not in Running or Succeeded state", failedPods)
+		return result, nil
+	}
+
+	result.Passed = true
+	result.Message = "All pods are in Running or Succeeded state"
+	return result, nil
+}
+
+// podFinding describes the state of a pod. The reason and message are taken
+// from the pod status or the first waiting or terminated container.
+func podFinding(pod corev1.Pod) checker.Finding {
+	phase := pod.Status.Phase
+	finding := checker.Finding{
+		Kind:      "Pod",
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		Healthy:   phase == corev1.PodRunning || phase == corev1.PodSucceeded,
+		Status:    string(phase),
+		Reason:    pod.Status.Reason,
+		Message:   pod.Status.Message,
+		CreatedAt: pod.CreationTimestamp.Time,
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.LastTransitionTime.Time.After(finding.LastTransition) {
+			finding.LastTransition = condition.LastTransitionTime.Time
+		}
+	}
 
-	if len(failedPods) > 0 {
-		fmt.Printf("\033[31mFailed pods:\033[0m\n")
-		for _, pod := range failedPods {
-			fmt.Printf("  - %s\n", pod)
+	if finding.Reason == "" {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Waiting != nil {
+				finding.Reason = status.State.Waiting.Reason
+				finding.Message = status.State.Waiting.Message
+				break
+			}
+			if status.State.Terminated != nil && status.State.Terminated.ExitCode != 0 {
+				finding.Reason = status.State.Terminated.Reason
+				finding.Message = status.State.Terminated.Message
+				break
+			}
 		}
-		return fmt.Errorf("%d pods not in Running or Succeeded state", len(failedPods))
 	}
 
-	return nil
+	return finding
 }
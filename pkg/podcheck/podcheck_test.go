@@ -1,9 +1,15 @@
 package podcheck
 
 import (
+	"context"
 	"os"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestCheckPodsWithInvalidConfig(t *testing.T) {
@@ -18,7 +24,7 @@ func TestCheckPodsWithInvalidConfig(t *testing.T) {
 	// Set invalid kubeconfig path
 	os.Setenv("KUBECONFIG", "/nonexistent/path/to/kubeconfig")
 
-	err := CheckPods("", false)
+	_, err := CheckPods(context.Background(), checker.KubeOptions{}, "", checker.Retry{}, false)
 	if err == nil {
 		t.Error("Expected error for invalid kubeconfig, got nil")
 	}
@@ -27,3 +33,115 @@ func TestCheckPodsWithInvalidConfig(t *testing.T) {
 		t.Errorf("Expected 'failed to build config' error, got: %v", err)
 	}
 }
+
+func TestCheckerWithInvalidConfig(t *testing.T) {
+	originalKubeConfig := os.Getenv("KUBECONFIG")
+	defer os.Setenv("KUBECONFIG", originalKubeConfig)
+
+	os.Setenv("KUBECONFIG", "/nonexistent/path/to/kubeconfig")
+
+	c := NewChecker(checker.Options{})
+	if c.Category() != checker.CategoryPods {
+		t.Errorf("Expected category '%s', got '%s'", checker.CategoryPods, c.Category())
+	}
+
+	results := c.Run(context.Background())
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 result, got %d", len(results))
+	}
+
+	if results[0].Passed {
+		t.Error("Expected result to fail for invalid kubeconfig")
+	}
+
+	if results[0].Name != "Pod Health" {
+		t.Errorf("Expected name 'Pod Health', got '%s'", results[0].Name)
+	}
+}
+
+func TestPodFinding(t *testing.T) {
+	created := metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	transition := metav1.NewTime(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name            string
+		pod             corev1.Pod
+		expectedHealthy bool
+		expectedReason  string
+	}{
+		{
+			name: "running pod",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app", CreationTimestamp: created},
+				Status: corev1.PodStatus{
+					Phase:      corev1.PodRunning,
+					Conditions: []corev1.PodCondition{{Type: corev1.PodReady, LastTransitionTime: transition}},
+				},
+			},
+			expectedHealthy: true,
+		},
+		{
+			name: "succeeded pod",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "job"},
+				Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+			},
+			expectedHealthy: true,
+		},
+		{
+			name: "pending pod with waiting container",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "broken"},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodPending,
+					ContainerStatuses: []corev1.ContainerStatus{{
+						State: corev1.ContainerState{
+							Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "image not found"},
+						},
+					}},
+				},
+			},
+			expectedHealthy: false,
+			expectedReason:  "ImagePullBackOff",
+		},
+		{
+			name: "evicted pod",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "evicted"},
+				Status:     corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted", Message: "low on memory"},
+			},
+			expectedHealthy: false,
+			expectedReason:  "Evicted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finding := podFinding(tt.pod)
+
+			if finding.Kind != "Pod" {
+				t.Errorf("Expected kind 'Pod', got '%s'", finding.Kind)
+			}
+			if finding.ObjectName() != tt.pod.Namespace+"/"+tt.pod.Name {
+				t.Errorf("Unexpected object name '%s'", finding.ObjectName())
+			}
+			if finding.Healthy != tt.expectedHealthy {
+				t.Errorf("Expected healthy %v, got %v", tt.expectedHealthy, finding.Healthy)
+			}
+			if finding.Status != string(tt.pod.Status.Phase) {
+				t.Errorf("Expected status '%s', got '%s'", tt.pod.Status.Phase, finding.Status)
+			}
+			if finding.Reason != tt.expectedReason {
+				t.Errorf("Expected reason '%s', got '%s'", tt.expectedReason, finding.Reason)
+			}
+		})
+	}
+
+	finding := podFinding(tests[0].pod)
+	if !finding.CreatedAt.Equal(created.Time) {
+		t.Errorf("Expected CreatedAt %v, got %v", created.Time, finding.CreatedAt)
+	}
+	if !finding.LastTransition.Equal(transition.Time) {
+		t.Errorf("Expected LastTransition %v, got %v", transition.Time, finding.LastTransition)
+	}
+}
@@ -0,0 +1,209 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"github.com/eumel8/clustercheck/pkg/gatecheck"
+	"github.com/mattn/go-runewidth"
+)
+
+// FleetJSONReport is the machine readable representation of a fleet run
+type FleetJSONReport struct {
+	SchemaVersion string           `json:"schemaVersion"`
+	Kind          string           `json:"kind"`
+	StartedAt     time.Time        `json:"startedAt"`
+	Duration      float64          `json:"durationSeconds"`
+	Summary       FleetJSONSummary `json:"summary"`
+	Clusters      []JSONReport     `json:"clusters"`
+}
+
+// FleetJSONSummary holds the number of passed and failed clusters of a fleet run
+type FleetJSONSummary struct {
+	TotalClusters  int      `json:"totalClusters"`
+	PassedClusters int      `json:"passedClusters"`
+	FailedClusters int      `json:"failedClusters"`
+	FailedContexts []string `json:"failedContexts"`
+	OverallPassed  bool     `json:"overallPassed"`
+}
+
+// NewFleetJSONReport converts a fleet run into its JSON representation
+func NewFleetJSONReport(res *gatecheck.FleetResult) FleetJSONReport {
+	report := FleetJSONReport{
+		SchemaVersion: SchemaVersion,
+		Kind:          KindFleetReport,
+		StartedAt:     res.StartedAt,
+		Duration:      res.Duration.Seconds(),
+		Summary: FleetJSONSummary{
+			TotalClusters:  res.TotalClusters,
+			PassedClusters: res.PassedClusters,
+			FailedClusters: res.FailedClusters,
+			FailedContexts: []string{},
+			OverallPassed:  res.OverallPassed,
+		},
+		Clusters: []JSONReport{},
+	}
+
+	for _, cluster := range res.Failed() {
+		report.Summary.FailedContexts = append(report.Summary.FailedContexts, cluster.Context)
+	}
+	for _, cluster := range res.Clusters {
+		report.Clusters = append(report.Clusters, NewJSONReport(cluster))
+	}
+	return report
+}
+
+// FleetJSON writes the fleet run as indented JSON document
+func FleetJSON(w io.Writer, res *gatecheck.FleetResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(NewFleetJSONReport(res))
+}
+
+// fleetStatus returns the matrix symbol and ANSI color of a check result
+func fleetStatus(check checker.Result) (string, string) {
+	switch {
+	case check.Passed:
+		return "✓", "32"
+	case check.TimedOut:
+		return "⏱", "35"
+	case check.Warning():
+		return "⚠", "33"
+	default:
+		return "✗", "31"
+	}
+}
+
+// FleetText writes the terminal output of a fleet run: a matrix of the
+// clusters and checks, the failed checks and the fleet summary
+func FleetText(w io.Writer, res *gatecheck.FleetResult) {
+	printBox(w, fmt.Sprintf("CLUSTER FLEET CHECK - %d clusters", res.TotalClusters))
+
+	checks := res.Checks()
+	contextWidth := runewidth.StringWidth("Cluster")
+	for _, cluster := range res.Clusters {
+		contextWidth = max(contextWidth, runewidth.StringWidth(cluster.Context))
+	}
+
+	// header row with the cluster, score and check columns
+	fmt.Fprintf(w, "\033[1m%s  %6s", runewidth.FillRight("Cluster", contextWidth), "Score")
+	for _, name := range checks {
+		fmt.Fprintf(w, "  %s", name)
+	}
+	fmt.Fprintf(w, "\033[0m\n")
+
+	for _, cluster := range res.Clusters {
+		color := "32"
+		if !cluster.OverallPassed {
+			color = "31"
+		}
+		fmt.Fprintf(w, "\033[%sm%s\033[0m  %5.1f%%", color, runewidth.FillRight(cluster.Context, contextWidth), cluster.HealthScore)
+		for _, name := range checks {
+			symbol, color := "-", "90"
+			if check, ok := cluster.Check(name); ok {
+				symbol, color = fleetStatus(check)
+			}
+			// the symbol is centered below the check name
+			width := runewidth.StringWidth(name)
+			left := (width - runewidth.StringWidth(symbol)) / 2
+			right := width - runewidth.StringWidth(symbol) - left
+			fmt.Fprintf(w, "  %s\033[%sm%s\033[0m%s", strings.Repeat(" ", left), color, symbol, strings.Repeat(" ", right))
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w)
+
+	failed := res.Failed()
+	if len(failed) > 0 {
+		fmt.Fprintln(w, "Failed Checks:")
+		fmt.Fprintln(w, "─────────────────────────────────────────────────")
+		for _, cluster := range failed {
+			fmt.Fprintf(w, "\033[1m%s\033[0m (%.1f%%)\n", cluster.Context, cluster.HealthScore)
+			for _, check := range cluster.CheckResults {
+				if check.Passed || check.Warning() {
+					continue
+				}
+				fmt.Fprintf(w, "  ✗ \033[31m%s\033[0m - %s\n", check.Name, check.Message)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	printBox(w, "FLEET CHECK SUMMARY")
+	if res.OverallPassed {
+		fmt.Fprintf(w, "\033[1;32m✓ FLEET HEALTH: PASSED\033[0m\n")
+	} else {
+		fmt.Fprintf(w, "\033[1;31m✗ FLEET HEALTH: FAILED\033[0m\n")
+	}
+	fmt.Fprintf(w, "\n\033[1mClusters: %d of %d passed the gate check\033[0m\n", res.PassedClusters, res.TotalClusters)
+	if len(failed) > 0 {
+		contexts := []string{}
+		for _, cluster := range failed {
+			contexts = append(contexts, cluster.Context)
+		}
+		fmt.Fprintf(w, "\033[31mFailed clusters: %s\033[0m\n", strings.Join(contexts, ", "))
+	}
+	fmt.Fprintln(w)
+}
+
+// FleetMarkdown writes the fleet run as Markdown summary with the matrix of
+// the clusters and checks
+func FleetMarkdown(w io.Writer, res *gatecheck.FleetResult) error {
+	var b strings.Builder
+
+	status := "✅ PASSED"
+	if !res.OverallPassed {
+		status = "❌ FAILED"
+	}
+	fmt.Fprintf(&b, "## Cluster Fleet Check\n\n")
+	fmt.Fprintf(&b, "**Fleet Health:** %s  \n", status)
+	fmt.Fprintf(&b, "**Clusters:** %d of %d passed the gate check\n\n", res.PassedClusters, res.TotalClusters)
+
+	checks := res.Checks()
+	b.WriteString("| Cluster | Score | Quality Gate |")
+	for _, name := range checks {
+		fmt.Fprintf(&b, " %s |", escapeMarkdown(name))
+	}
+	b.WriteString("\n|---------|-------|--------------|" + strings.Repeat("---|", len(checks)) + "\n")
+
+	for _, cluster := range res.Clusters {
+		tier, _ := cluster.QualityGate()
+		fmt.Fprintf(&b, "| %s | %.1f%% | %s %s |", escapeMarkdown(cluster.Context), cluster.HealthScore, qualityGateEmojis[tier], tier)
+		for _, name := range checks {
+			check, ok := cluster.Check(name)
+			switch {
+			case !ok:
+				b.WriteString(" - |")
+			case check.Passed:
+				b.WriteString(" ✅ |")
+			case check.TimedOut:
+				b.WriteString(" ⏱️ |")
+			case check.Warning():
+				b.WriteString(" ⚠️ |")
+			default:
+				b.WriteString(" ❌ |")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	failed := res.Failed()
+	if len(failed) > 0 {
+		b.WriteString("\n### Failure Details\n\n")
+		for _, cluster := range failed {
+			for _, check := range cluster.CheckResults {
+				if !check.Passed {
+					check.Name = cluster.Context + ": " + check.Name
+					markdownDetails(&b, check)
+				}
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
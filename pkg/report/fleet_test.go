@@ -0,0 +1,96 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/eumel8/clustercheck/pkg/gatecheck"
+)
+
+// testFleetResult returns a fleet result with a passed and the failed test cluster
+func testFleetResult() *gatecheck.FleetResult {
+	passed := &gatecheck.GateCheckResult{
+		Context:       "prod",
+		TotalChecks:   1,
+		PassedChecks:  1,
+		HealthScore:   100,
+		OverallPassed: true,
+		CheckResults:  []gatecheck.CheckResult{{Name: "Pod Health", Category: "pods", Passed: true}},
+	}
+	return &gatecheck.FleetResult{
+		TotalClusters:  2,
+		PassedClusters: 1,
+		FailedClusters: 1,
+		Clusters:       []*gatecheck.GateCheckResult{passed, testResult()},
+	}
+}
+
+func TestFleetText(t *testing.T) {
+	var buf bytes.Buffer
+	FleetText(&buf, testFleetResult())
+	output := buf.String()
+
+	expected := []string{
+		"CLUSTER FLEET CHECK - 2 clusters",
+		"Cluster        Score  Pod Health  Flux Resources  APISERVER",
+		"\033[32mprod        \033[0m  100.0%      \033[32m✓\033[0m             \033[90m-\033[0m             \033[90m-\033[0m    ",
+		"\033[31mtest-context\033[0m   33.3%      \033[31m✗\033[0m             \033[32m✓\033[0m             \033[31m✗\033[0m    ",
+		"  ✗ \033[31mAPISERVER\033[0m - Value: 0 (expected: 1)",
+		"✗ FLEET HEALTH: FAILED",
+		"Clusters: 1 of 2 passed the gate check",
+		"Failed clusters: test-context",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected output to contain %q, got:\n%s", e, output)
+		}
+	}
+}
+
+func TestFleetJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FleetJSON(&buf, testFleetResult()); err != nil {
+		t.Fatalf("FleetJSON() returned error: %v", err)
+	}
+
+	var report FleetJSONReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+
+	if report.SchemaVersion != SchemaVersion || report.Kind != KindFleetReport {
+		t.Errorf("Expected schema version '%s' and kind '%s', got '%s' and '%s'", SchemaVersion, KindFleetReport, report.SchemaVersion, report.Kind)
+	}
+	if report.Summary.TotalClusters != 2 || report.Summary.FailedClusters != 1 || report.Summary.OverallPassed {
+		t.Errorf("Unexpected summary: %+v", report.Summary)
+	}
+	if len(report.Summary.FailedContexts) != 1 || report.Summary.FailedContexts[0] != "test-context" {
+		t.Errorf("Expected failed context test-context, got %v", report.Summary.FailedContexts)
+	}
+	if len(report.Clusters) != 2 || report.Clusters[1].Context != "test-context" || len(report.Clusters[1].Checks) != 3 || report.Clusters[1].Kind != KindGateCheckReport {
+		t.Errorf("Expected the reports of both clusters, got %+v", report.Clusters)
+	}
+}
+
+func TestFleetMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FleetMarkdown(&buf, testFleetResult()); err != nil {
+		t.Fatalf("FleetMarkdown() returned error: %v", err)
+	}
+	output := buf.String()
+
+	expected := []string{
+		"**Fleet Health:** ❌ FAILED",
+		"| Cluster | Score | Quality Gate | Pod Health | Flux Resources | APISERVER |",
+		"| prod | 100.0% | 🟢 EXCELLENT | ✅ | - | - |",
+		"| test-context | 33.3% | 🔴 POOR | ❌ | ✅ | ❌ |",
+		"<summary>test-context: APISERVER - Value: 0 (expected: 1)</summary>",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected output to contain %q, got:\n%s", e, output)
+		}
+	}
+}
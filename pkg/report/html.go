@@ -0,0 +1,155 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"github.com/eumel8/clustercheck/pkg/gatecheck"
+)
+
+// gaugeCircumference is the circumference of the health score gauge circle (r=54)
+var gaugeCircumference = 2 * math.Pi * 54
+
+// qualityGateColors are the colors of the quality gate tiers
+var qualityGateColors = map[string]string{
+	"EXCELLENT": "#2e7d32",
+	"GOOD":      "#9e9d24",
+	"FAIR":      "#ef6c00",
+	"POOR":      "#c62828",
+}
+
+// htmlSection holds the checks of one category for the HTML report
+type htmlSection struct {
+	Title  string
+	Checks []checker.Result
+}
+
+// htmlData is passed to the HTML report template
+type htmlData struct {
+	Result      *gatecheck.GateCheckResult
+	GeneratedAt string
+	Tier        string
+	Decision    string
+	Color       string
+	GaugeDash   string
+	GaugeGap    string
+	Sections    []htmlSection
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"seconds": func(d time.Duration) string { return fmt.Sprintf("%.2fs", d.Seconds()) },
+	"timestamp": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Cluster Gate Check - {{.Result.Context}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+header { display: flex; align-items: center; gap: 2em; border-bottom: 1px solid #ddd; padding-bottom: 1em; }
+h1 { margin: 0 0 .3em 0; font-size: 1.6em; }
+.meta { color: #666; }
+.tier { font-size: 1.3em; font-weight: bold; }
+table { border-collapse: collapse; width: 100%; margin: 1em 0; }
+th, td { border: 1px solid #ddd; padding: .4em .6em; text-align: left; vertical-align: top; }
+th { background: #f5f5f5; }
+.pass { color: #2e7d32; font-weight: bold; }
+.fail { color: #c62828; font-weight: bold; }
+.warn { color: #ef6c00; font-weight: bold; }
+.timeout { color: #6a1b9a; font-weight: bold; }
+details { margin: .5em 0; border: 1px solid #ddd; border-radius: 4px; padding: .5em; }
+summary { cursor: pointer; font-weight: bold; }
+pre { background: #f5f5f5; padding: .5em; overflow-x: auto; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<header>
+<svg width="140" height="140" viewBox="0 0 140 140" role="img" aria-label="Health score {{printf "%.1f" .Result.HealthScore}}%">
+<circle cx="70" cy="70" r="54" fill="none" stroke="#eee" stroke-width="14"/>
+<circle cx="70" cy="70" r="54" fill="none" stroke="{{.Color}}" stroke-width="14" stroke-dasharray="{{.GaugeDash}} {{.GaugeGap}}" transform="rotate(-90 70 70)"/>
+<text x="70" y="78" text-anchor="middle" font-size="24" font-weight="bold" fill="{{.Color}}">{{printf "%.1f" .Result.HealthScore}}%</text>
+</svg>
+<div>
+<h1>Cluster Gate Check - {{.Result.Context}}</h1>
+<div class="meta">Started {{timestamp .Result.StartedAt}}, duration {{seconds .Result.Duration}}, generated {{.GeneratedAt}}</div>
+<p>{{if .Result.OverallPassed}}<span class="pass">✓ CLUSTER HEALTH: PASSED</span>{{else}}<span class="fail">✗ CLUSTER HEALTH: FAILED</span>{{end}}
+({{.Result.PassedChecks}} of {{.Result.TotalChecks}} checks passed{{if .Result.WarningChecks}}, {{.Result.WarningChecks}} warnings{{end}}{{if .Result.TimedOutChecks}}, {{.Result.TimedOutChecks}} timed out{{end}})</p>
+<div class="tier" style="color: {{.Color}}">Quality Gate Decision: {{.Tier}} - {{.Decision}}</div>
+</div>
+</header>
+
+<h2>Results</h2>
+<table>
+<tr><th>Check</th><th>Category</th><th>Status</th><th>Message</th><th>Duration</th></tr>
+{{- range .Result.CheckResults}}
+<tr><td>{{.Name}}</td><td>{{.Category}}</td><td>{{if .Passed}}<span class="pass">PASS</span>{{else if .TimedOut}}<span class="timeout">TIMEOUT</span>{{else if .Warning}}<span class="warn">WARN</span>{{else}}<span class="fail">FAIL</span>{{end}}</td><td>{{.Message}}</td><td>{{seconds .Duration}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Details</h2>
+{{- range .Sections}}
+<h3>{{.Title}}</h3>
+{{- range .Checks}}
+<details{{if not .Passed}} open{{end}}>
+<summary>{{if .Passed}}<span class="pass">✓</span>{{else if .TimedOut}}<span class="timeout">⏱</span>{{else if .Warning}}<span class="warn">⚠</span>{{else}}<span class="fail">✗</span>{{end}} {{.Name}} - {{.Message}}</summary>
+{{- if .Target}}
+<p>Target: {{.Target}}{{if .TargetSource}} ({{.TargetSource}}){{end}}</p>
+{{- end}}
+{{- if .Query}}
+<p>Query:</p>
+<pre>{{.Query}}</pre>
+{{- end}}
+{{- if .Value}}
+<p>Value: <code>{{.Value}}</code></p>
+{{- end}}
+{{- if .Endpoint}}
+<p>Endpoint: {{.Endpoint}}</p>
+{{- end}}
+{{- with .Failed}}
+<table>
+<tr><th>Kind</th><th>Object</th><th>Status</th><th>Reason</th><th>Message</th><th>Last Transition</th></tr>
+{{- range .}}
+<tr><td>{{.Kind}}</td><td>{{.ObjectName}}</td><td>{{.Status}}</td><td>{{.Reason}}</td><td>{{.Message}}</td><td>{{timestamp .LastTransition}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</details>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// HTML writes the run as self-contained HTML report without external assets
+func HTML(w io.Writer, res *gatecheck.GateCheckResult) error {
+	tier, decision := res.QualityGate()
+	dash := gaugeCircumference * math.Max(0, math.Min(res.HealthScore, 100)) / 100
+
+	data := htmlData{
+		Result:      res,
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Tier:        tier,
+		Decision:    decision,
+		Color:       qualityGateColors[tier],
+		GaugeDash:   fmt.Sprintf("%.2f", dash),
+		GaugeGap:    fmt.Sprintf("%.2f", gaugeCircumference-dash),
+	}
+
+	for _, category := range res.Categories() {
+		data.Sections = append(data.Sections, htmlSection{
+			Title:  Title(category),
+			Checks: res.Results(category),
+		})
+	}
+
+	return htmlTemplate.Execute(w, data)
+}
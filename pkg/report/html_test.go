@@ -0,0 +1,38 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	res := testResult()
+	res.CheckResults[0].Findings[1].Message = "<script>alert(1)</script>"
+
+	var buf bytes.Buffer
+	if err := HTML(&buf, res); err != nil {
+		t.Fatalf("HTML() returned error: %v", err)
+	}
+	output := buf.String()
+
+	expected := []string{
+		"<title>Cluster Gate Check - test-context</title>",
+		"Quality Gate Decision: POOR - Not ready for production",
+		"33.3%",
+		"<td>default/broken</td>",
+		"avg(up{job=&#34;kube-apiserver&#34;})",
+		"&lt;script&gt;",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected output to contain %q", e)
+		}
+	}
+
+	for _, external := range []string{"<script", "<link", "src=\"http", "href=\"http"} {
+		if strings.Contains(output, external) {
+			t.Errorf("Expected self-contained report, found %q", external)
+		}
+	}
+}
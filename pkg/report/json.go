@@ -0,0 +1,160 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"github.com/eumel8/clustercheck/pkg/gatecheck"
+)
+
+// SchemaVersion is the version of the JSON output schema. It is increased on
+// every incompatible change of the document structure.
+const SchemaVersion = "clustercheck.eumel8.github.com/v1"
+
+// Kinds of the JSON documents, to tell a single cluster report from a fleet
+// report of the same schema version
+const (
+	KindGateCheckReport = "GateCheckReport"
+	KindFleetReport     = "FleetReport"
+)
+
+// JSONReport is the machine readable representation of a run
+type JSONReport struct {
+	SchemaVersion string      `json:"schemaVersion"`
+	Kind          string      `json:"kind"`
+	Context       string      `json:"context"`
+	StartedAt     time.Time   `json:"startedAt"`
+	Duration      float64     `json:"durationSeconds"`
+	Summary       JSONSummary `json:"summary"`
+	Checks        []JSONCheck `json:"checks"`
+}
+
+// JSONSummary holds the health score and quality gate decision of a run
+type JSONSummary struct {
+	TotalChecks    int     `json:"totalChecks"`
+	PassedChecks   int     `json:"passedChecks"`
+	FailedChecks   int     `json:"failedChecks"`
+	WarningChecks  int     `json:"warningChecks"`
+	TimedOutChecks int     `json:"timedOutChecks"`
+	HealthScore    float64 `json:"healthScore"`
+	OverallPassed  bool    `json:"overallPassed"`
+	QualityGate    string  `json:"qualityGate"`
+	Decision       string  `json:"decision"`
+}
+
+// JSONCheck is the result of a single check
+type JSONCheck struct {
+	Name         string        `json:"name"`
+	Category     string        `json:"category"`
+	Passed       bool          `json:"passed"`
+	Severity     string        `json:"severity,omitempty"`
+	TimedOut     bool          `json:"timedOut,omitempty"`
+	Attempts     int           `json:"attempts,omitempty"`
+	Message      string        `json:"message"`
+	Target       string        `json:"target,omitempty"`
+	TargetSource string        `json:"targetSource,omitempty"`
+	Query        string        `json:"query,omitempty"`
+	Value        string        `json:"value,omitempty"`
+	Endpoint     string        `json:"endpoint,omitempty"`
+	StartedAt    time.Time     `json:"startedAt"`
+	Duration     float64       `json:"durationSeconds"`
+	Findings     []JSONFinding `json:"findings"`
+}
+
+// JSONFinding is the state of a single object inspected by a check
+type JSONFinding struct {
+	Kind           string            `json:"kind"`
+	Namespace      string            `json:"namespace,omitempty"`
+	Name           string            `json:"name"`
+	Healthy        bool              `json:"healthy"`
+	Status         string            `json:"status"`
+	Reason         string            `json:"reason,omitempty"`
+	Message        string            `json:"message,omitempty"`
+	Revision       string            `json:"revision,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	CreatedAt      *time.Time        `json:"createdAt,omitempty"`
+	LastTransition *time.Time        `json:"lastTransition,omitempty"`
+}
+
+// NewJSONReport converts a run into its JSON representation
+func NewJSONReport(res *gatecheck.GateCheckResult) JSONReport {
+	tier, decision := res.QualityGate()
+	report := JSONReport{
+		SchemaVersion: SchemaVersion,
+		Kind:          KindGateCheckReport,
+		Context:       res.Context,
+		StartedAt:     res.StartedAt,
+		Duration:      res.Duration.Seconds(),
+		Summary: JSONSummary{
+			TotalChecks:    res.TotalChecks,
+			PassedChecks:   res.PassedChecks,
+			FailedChecks:   res.FailedChecks,
+			WarningChecks:  res.WarningChecks,
+			TimedOutChecks: res.TimedOutChecks,
+			HealthScore:    res.HealthScore,
+			OverallPassed:  res.OverallPassed,
+			QualityGate:    tier,
+			Decision:       decision,
+		},
+		Checks: []JSONCheck{},
+	}
+
+	for _, check := range res.CheckResults {
+		report.Checks = append(report.Checks, newJSONCheck(check))
+	}
+	return report
+}
+
+func newJSONCheck(check checker.Result) JSONCheck {
+	jsonCheck := JSONCheck{
+		Name:         check.Name,
+		Category:     check.Category,
+		Passed:       check.Passed,
+		Severity:     check.Severity,
+		TimedOut:     check.TimedOut,
+		Attempts:     check.Attempts,
+		Message:      check.Message,
+		Target:       check.Target,
+		TargetSource: check.TargetSource,
+		Query:        check.Query,
+		Value:        check.Value,
+		Endpoint:     check.Endpoint,
+		StartedAt:    check.StartedAt,
+		Duration:     check.Duration.Seconds(),
+		Findings:     []JSONFinding{},
+	}
+
+	for _, finding := range check.Findings {
+		jsonCheck.Findings = append(jsonCheck.Findings, JSONFinding{
+			Kind:           finding.Kind,
+			Namespace:      finding.Namespace,
+			Name:           finding.Name,
+			Healthy:        finding.Healthy,
+			Status:         finding.Status,
+			Reason:         finding.Reason,
+			Message:        finding.Message,
+			Revision:       finding.Revision,
+			Labels:         finding.Labels,
+			CreatedAt:      timePtr(finding.CreatedAt),
+			LastTransition: timePtr(finding.LastTransition),
+		})
+	}
+	return jsonCheck
+}
+
+// timePtr returns nil for the zero time so it is omitted from the output
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// JSON writes the run as indented JSON document
+func JSON(w io.Writer, res *gatecheck.GateCheckResult) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(NewJSONReport(res))
+}
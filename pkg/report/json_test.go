@@ -0,0 +1,65 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := JSON(&buf, testResult()); err != nil {
+		t.Fatalf("JSON() returned error: %v", err)
+	}
+
+	var report JSONReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+
+	if report.SchemaVersion != SchemaVersion {
+		t.Errorf("Expected schema version '%s', got '%s'", SchemaVersion, report.SchemaVersion)
+	}
+	if report.Kind != KindGateCheckReport {
+		t.Errorf("Expected kind '%s', got '%s'", KindGateCheckReport, report.Kind)
+	}
+
+	if report.Summary.TotalChecks != 3 || report.Summary.FailedChecks != 2 {
+		t.Errorf("Unexpected summary: %+v", report.Summary)
+	}
+
+	if report.Summary.QualityGate != "POOR" {
+		t.Errorf("Expected quality gate 'POOR', got '%s'", report.Summary.QualityGate)
+	}
+
+	if len(report.Checks) != 3 {
+		t.Fatalf("Expected 3 checks, got %d", len(report.Checks))
+	}
+
+	pods := report.Checks[0]
+	if len(pods.Findings) != 2 || pods.Findings[1].Reason != "ImagePullBackOff" {
+		t.Errorf("Expected pod findings to be serialized, got %+v", pods.Findings)
+	}
+
+	if pods.Findings[0].CreatedAt != nil {
+		t.Error("Expected zero timestamps to be omitted")
+	}
+
+	prometheus := report.Checks[2]
+	if prometheus.Query == "" || prometheus.Value != "0" || prometheus.Endpoint != "https://prometheus.example.com" || prometheus.TargetSource != "cluster map" {
+		t.Errorf("Expected query, value, endpoint and target source to be serialized, got %+v", prometheus)
+	}
+}
+
+func TestJSONEmptyFindings(t *testing.T) {
+	var buf bytes.Buffer
+	res := testResult()
+	res.CheckResults[2].Findings = nil
+	if err := JSON(&buf, res); err != nil {
+		t.Fatalf("JSON() returned error: %v", err)
+	}
+
+	if !bytes.Contains(buf.Bytes(), []byte(`"findings": []`)) {
+		t.Error("Expected empty findings to be serialized as empty array")
+	}
+}
@@ -0,0 +1,173 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"github.com/eumel8/clustercheck/pkg/gatecheck"
+)
+
+// JUnitTestSuites is the root element of a JUnit XML report
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite holds the test cases of one check category
+type JUnitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []JUnitProperty `xml:"properties>property,omitempty"`
+	Cases      []JUnitTestCase `xml:"testcase"`
+}
+
+// JUnitProperty is a name/value pair attached to a test suite
+type JUnitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// JUnitTestCase is the result of a single check
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// JUnitFailure describes why a check failed
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// NewJUnitReport converts a run into a JUnit report with one test suite per check category
+func NewJUnitReport(res *gatecheck.GateCheckResult) JUnitTestSuites {
+	suites := JUnitTestSuites{
+		Name:     fmt.Sprintf("clustercheck %s", res.Context),
+		Tests:    len(res.CheckResults),
+		Failures: res.FailedChecks,
+		Time:     seconds(res.Duration.Seconds()),
+		Suites:   []JUnitTestSuite{},
+	}
+
+	for _, category := range res.Categories() {
+		suite := JUnitTestSuite{
+			Name: Title(category),
+			Properties: []JUnitProperty{
+				{Name: "context", Value: res.Context},
+				{Name: "category", Value: category},
+			},
+			Cases: []JUnitTestCase{},
+		}
+
+		total := 0.0
+		for _, check := range res.Results(category) {
+			if suite.Timestamp == "" && !check.StartedAt.IsZero() {
+				suite.Timestamp = check.StartedAt.UTC().Format("2006-01-02T15:04:05")
+			}
+			total += check.Duration.Seconds()
+
+			testCase := JUnitTestCase{
+				Name:      check.Name,
+				ClassName: "clustercheck." + category,
+				Time:      seconds(check.Duration.Seconds()),
+				SystemOut: details(check),
+			}
+			if check.TimedOut && !check.Warning() {
+				suite.Failures++
+				testCase.Failure = &JUnitFailure{
+					Message: check.Message,
+					Type:    "Timeout",
+					Text:    check.Message,
+				}
+			} else if check.Warning() {
+				testCase.SystemOut = strings.TrimSpace("WARNING: " + check.Message + "\n" + testCase.SystemOut)
+			} else if !check.Passed {
+				suite.Failures++
+				testCase.Failure = &JUnitFailure{
+					Message: check.Message,
+					Type:    "CheckFailed",
+					Text:    failureDetails(check),
+				}
+			}
+			suite.Tests++
+			suite.Cases = append(suite.Cases, testCase)
+		}
+		suite.Time = seconds(total)
+
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	return suites
+}
+
+// seconds formats a duration in seconds as expected by JUnit consumers
+func seconds(s float64) string {
+	return fmt.Sprintf("%.3f", s)
+}
+
+// details returns the query, observed value and retries of a check, if any
+func details(check checker.Result) string {
+	lines := []string{}
+	if check.Target != "" && check.TargetSource != "" {
+		lines = append(lines, fmt.Sprintf("Target: %s (%s)", check.Target, check.TargetSource))
+	} else if check.Target != "" {
+		lines = append(lines, "Target: "+check.Target)
+	}
+	if check.Query != "" {
+		lines = append(lines, "Query: "+check.Query)
+	}
+	if check.Value != "" {
+		lines = append(lines, "Value: "+check.Value)
+	}
+	if check.Endpoint != "" {
+		lines = append(lines, "Endpoint: "+check.Endpoint)
+	}
+	if check.Attempts > 1 {
+		lines = append(lines, fmt.Sprintf("Attempts: %d", check.Attempts))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// failureDetails lists the message and the unhealthy objects of a failed check
+func failureDetails(check checker.Result) string {
+	lines := []string{check.Message}
+	for _, finding := range check.Failed() {
+		line := fmt.Sprintf("%s %s: %s", finding.Kind, finding.ObjectName(), finding.Status)
+		if finding.Reason != "" {
+			line += " (" + finding.Reason + ")"
+		}
+		if finding.Message != "" {
+			line += " - " + finding.Message
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// JUnit writes the run as JUnit XML report
+func JUnit(w io.Writer, res *gatecheck.GateCheckResult) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(NewJUnitReport(res)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
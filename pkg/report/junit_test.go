@@ -0,0 +1,76 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJUnit(t *testing.T) {
+	res := testResult()
+	res.CheckResults[0].Duration = 1500 * time.Millisecond
+
+	var buf bytes.Buffer
+	if err := JUnit(&buf, res); err != nil {
+		t.Fatalf("JUnit() returned error: %v", err)
+	}
+
+	if !strings.HasPrefix(buf.String(), xml.Header) {
+		t.Error("Expected XML header")
+	}
+
+	var suites JUnitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("Failed to parse JUnit output: %v", err)
+	}
+
+	if suites.Tests != 3 || suites.Failures != 2 {
+		t.Errorf("Expected 3 tests with 2 failures, got %d/%d", suites.Tests, suites.Failures)
+	}
+
+	if len(suites.Suites) != 3 {
+		t.Fatalf("Expected one test suite per category, got %d", len(suites.Suites))
+	}
+
+	pods := suites.Suites[0]
+	if pods.Name != "Pod Health" || pods.Failures != 1 || pods.Time != "1.500" {
+		t.Errorf("Unexpected pod test suite: %+v", pods)
+	}
+
+	failure := pods.Cases[0].Failure
+	if failure == nil {
+		t.Fatal("Expected failure for pod test case")
+	}
+	if failure.Message != "1 pods not in Running or Succeeded state" {
+		t.Errorf("Unexpected failure message: %s", failure.Message)
+	}
+	if !strings.Contains(failure.Text, "Pod default/broken: Pending (ImagePullBackOff)") {
+		t.Errorf("Expected failing pod in failure details, got: %s", failure.Text)
+	}
+
+	if suites.Suites[1].Cases[0].Failure != nil {
+		t.Error("Expected no failure for passed Flux test case")
+	}
+
+	prometheus := suites.Suites[2].Cases[0]
+	if prometheus.ClassName != "clustercheck.prometheus" || !strings.Contains(prometheus.SystemOut, "Query: ") {
+		t.Errorf("Unexpected Prometheus test case: %+v", prometheus)
+	}
+}
+
+func TestJUnitTimeout(t *testing.T) {
+	res := testResult()
+	res.CheckResults[2].TimedOut = true
+	res.CheckResults[2].Message = "Query timed out after 30s"
+
+	report := NewJUnitReport(res)
+	failure := report.Suites[2].Cases[0].Failure
+	if failure == nil || failure.Type != "Timeout" {
+		t.Errorf("Expected timeout failure, got %+v", failure)
+	}
+	if report.Suites[0].Cases[0].Failure.Type != "CheckFailed" {
+		t.Errorf("Expected failed check to keep type CheckFailed, got %+v", report.Suites[0].Cases[0].Failure)
+	}
+}
@@ -0,0 +1,116 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"github.com/eumel8/clustercheck/pkg/gatecheck"
+)
+
+// qualityGateEmojis are the markers of the quality gate tiers
+var qualityGateEmojis = map[string]string{
+	"EXCELLENT": "🟢",
+	"GOOD":      "🟡",
+	"FAIR":      "🟠",
+	"POOR":      "🔴",
+}
+
+// Markdown writes the run as Markdown summary, e.g. for $GITHUB_STEP_SUMMARY
+// or pull request comments
+func Markdown(w io.Writer, res *gatecheck.GateCheckResult) error {
+	var b strings.Builder
+
+	status := "✅ PASSED"
+	if !res.OverallPassed {
+		status = "❌ FAILED"
+	}
+	tier, decision := res.QualityGate()
+
+	fmt.Fprintf(&b, "## Cluster Gate Check - %s\n\n", escapeMarkdown(res.Context))
+	fmt.Fprintf(&b, "**Cluster Health:** %s  \n", status)
+	fmt.Fprintf(&b, "**Health Score:** %.1f%% (%d of %d checks passed)  \n",
+		res.HealthScore, res.PassedChecks, res.TotalChecks)
+	if res.WarningChecks > 0 {
+		fmt.Fprintf(&b, "**Warnings:** %d (not counted in the health score)  \n", res.WarningChecks)
+	}
+	if res.TimedOutChecks > 0 {
+		fmt.Fprintf(&b, "**Timed out:** %d  \n", res.TimedOutChecks)
+	}
+	fmt.Fprintf(&b, "**Quality Gate:** %s %s - %s\n\n", qualityGateEmojis[tier], tier, decision)
+
+	b.WriteString("| Check | Category | Status | Message |\n")
+	b.WriteString("|-------|----------|--------|---------|\n")
+	for _, check := range res.CheckResults {
+		checkStatus := "✅ PASS"
+		if check.TimedOut {
+			checkStatus = "⏱️ TIMEOUT"
+		} else if check.Warning() {
+			checkStatus = "⚠️ WARN"
+		} else if !check.Passed {
+			checkStatus = "❌ FAIL"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n",
+			escapeMarkdown(check.Name), escapeMarkdown(Title(check.Category)), checkStatus, escapeMarkdown(check.Message))
+	}
+
+	failed := []checker.Result{}
+	for _, check := range res.CheckResults {
+		if !check.Passed {
+			failed = append(failed, check)
+		}
+	}
+
+	if len(failed) > 0 {
+		b.WriteString("\n### Failure Details\n\n")
+		for _, check := range failed {
+			markdownDetails(&b, check)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownDetails writes a collapsible section with the unhealthy objects of a failed check
+func markdownDetails(b *strings.Builder, check checker.Result) {
+	fmt.Fprintf(b, "<details>\n<summary>%s - %s</summary>\n\n",
+		escapeHTML(check.Name), escapeHTML(check.Message))
+
+	if check.Query != "" {
+		fmt.Fprintf(b, "Query:\n\n```promql\n%s\n```\n\n", check.Query)
+	}
+	if check.Value != "" {
+		fmt.Fprintf(b, "Value: `%s`\n\n", check.Value)
+	}
+	if check.Endpoint != "" {
+		fmt.Fprintf(b, "Endpoint: %s\n\n", check.Endpoint)
+	}
+
+	if failed := check.Failed(); len(failed) > 0 {
+		b.WriteString("| Kind | Object | Status | Reason | Message |\n")
+		b.WriteString("|------|--------|--------|--------|---------|\n")
+		for _, finding := range failed {
+			fmt.Fprintf(b, "| %s | %s | %s | %s | %s |\n",
+				escapeMarkdown(finding.Kind), escapeMarkdown(finding.ObjectName()), escapeMarkdown(finding.Status),
+				escapeMarkdown(finding.Reason), escapeMarkdown(finding.Message))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("</details>\n\n")
+}
+
+// escapeMarkdown makes text safe for use in a Markdown table cell
+func escapeMarkdown(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	text = strings.ReplaceAll(text, "\r\n", "<br>")
+	return strings.ReplaceAll(text, "\n", "<br>")
+}
+
+// escapeHTML escapes text used inside HTML elements of the Markdown output
+func escapeHTML(text string) string {
+	replacer := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\n", " ")
+	return replacer.Replace(text)
+}
@@ -0,0 +1,39 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	res := testResult()
+	res.CheckResults[0].Findings[1].Message = "pull | failed"
+
+	var buf bytes.Buffer
+	if err := Markdown(&buf, res); err != nil {
+		t.Fatalf("Markdown() returned error: %v", err)
+	}
+	output := buf.String()
+
+	expected := []string{
+		"## Cluster Gate Check - test-context",
+		"**Cluster Health:** ❌ FAILED",
+		"**Health Score:** 33.3% (1 of 3 checks passed)",
+		"**Quality Gate:** 🔴 POOR - Not ready for production",
+		"| Pod Health | Pod Health | ❌ FAIL | 1 pods not in Running or Succeeded state |",
+		"| Flux Resources | Flux Resources | ✅ PASS |",
+		"<summary>Pod Health - 1 pods not in Running or Succeeded state</summary>",
+		"| Pod | default/broken | Pending | ImagePullBackOff | pull \\| failed |",
+		"```promql\navg(up{job=\"kube-apiserver\"})\n```",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected output to contain %q, got:\n%s", e, output)
+		}
+	}
+
+	if strings.Contains(output, "<summary>Flux Resources") {
+		t.Error("Expected no failure details for passed checks")
+	}
+}
@@ -0,0 +1,261 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"github.com/eumel8/clustercheck/pkg/gatecheck"
+	"github.com/mattn/go-runewidth"
+)
+
+// categoryTitles are the section titles of the built-in check categories
+var categoryTitles = map[string]string{
+	checker.CategoryPods:       "Pod Health",
+	checker.CategoryFlux:       "Flux Resources",
+	checker.CategoryPrometheus: "Prometheus Monitoring",
+	checker.CategoryCAPI:       "Cluster API",
+}
+
+// Title returns the display title of a check category
+func Title(category string) string {
+	if title, ok := categoryTitles[category]; ok {
+		return title
+	}
+	return category
+}
+
+// Text writes the terminal output of a run. With gate set the sections are
+// framed by the gate check header, summary and quality gate decision.
+func Text(w io.Writer, res *gatecheck.GateCheckResult, gate bool) {
+	categories := res.Categories()
+
+	if gate {
+		printBox(w, fmt.Sprintf("CLUSTER GATE CHECK - %s", res.Context))
+	}
+
+	for i, category := range categories {
+		if gate {
+			fmt.Fprintf(w, "\033[1m[%d/%d] %s Check\033[0m\n", i+1, len(categories), Title(category))
+			fmt.Fprintln(w, "━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
+		}
+
+		results := res.Results(category)
+		switch category {
+		case checker.CategoryPods:
+			for _, result := range results {
+				printPods(w, res.Context, result)
+			}
+		case checker.CategoryFlux:
+			for _, result := range results {
+				printFlux(w, res.Context, result)
+			}
+		default:
+			printChecks(w, results)
+		}
+
+		if gate {
+			fmt.Fprintln(w)
+		}
+	}
+
+	if gate {
+		printSummary(w, res)
+	}
+}
+
+// printBox prints text framed by a box sized to the text width
+func printBox(w io.Writer, text string) {
+	textWidth := runewidth.StringWidth(text)
+	width := textWidth + 2
+
+	top := "╔" + strings.Repeat("═", width) + "╗"
+	bot := "╚" + strings.Repeat("═", width) + "╝"
+	mid := fmt.Sprintf("║ %s%s ║", text, strings.Repeat(" ", width-textWidth-1))
+
+	fmt.Fprintf(w, "\033[36m%s\033[0m\n", top)
+	fmt.Fprintf(w, "\033[36m%s\033[0m\n", mid)
+	fmt.Fprintf(w, "\033[36m%s\033[0m\n\n", bot)
+}
+
+// printError prints the message of a result which could not inspect any objects
+func printError(w io.Writer, result checker.Result) bool {
+	if result.Passed || len(result.Findings) > 0 {
+		return false
+	}
+	if result.TimedOut {
+		fmt.Fprintf(w, "\033[35m⏱ TIMEOUT: %s\033[0m\n", result.Message)
+		return true
+	}
+	fmt.Fprintf(w, "\033[31m✗ %s\033[0m\n", result.Message)
+	return true
+}
+
+func printPods(w io.Writer, context string, result checker.Result) {
+	fmt.Fprintf(w, "\033[36mpodcheck \033[0m on %s\n", context)
+	if printError(w, result) {
+		return
+	}
+
+	for _, pod := range result.Findings {
+		if pod.Healthy {
+			fmt.Fprintf(w, "%s \033[32m🟢 %s\033[0m\n", pod.ObjectName(), pod.Status)
+		} else {
+			fmt.Fprintf(w, "%s \033[31m🔴 %s\033[0m\n", pod.ObjectName(), pod.Status)
+		}
+	}
+
+	failed := result.Failed()
+	fmt.Fprintf(w, "\nSummary: %d/%d pods in Running or Succeeded state\n",
+		len(result.Findings)-len(failed), len(result.Findings))
+
+	if len(failed) > 0 {
+		fmt.Fprintf(w, "\033[31mFailed pods:\033[0m\n")
+		for _, pod := range failed {
+			if pod.Reason != "" {
+				fmt.Fprintf(w, "  - %s (%s: %s)\n", pod.ObjectName(), pod.Status, pod.Reason)
+			} else {
+				fmt.Fprintf(w, "  - %s (%s)\n", pod.ObjectName(), pod.Status)
+			}
+		}
+	}
+}
+
+func printFlux(w io.Writer, context string, result checker.Result) {
+	fmt.Fprintf(w, "\033[36mfluxcheck \033[0m on %s\n", context)
+	if printError(w, result) {
+		return
+	}
+
+	for _, kind := range []string{"HelmRelease", "Kustomization"} {
+		fmt.Fprintf(w, "\n\033[1m%ss:\033[0m\n", kind)
+		for _, resource := range result.Findings {
+			if resource.Kind != kind {
+				continue
+			}
+			switch {
+			case resource.Healthy:
+				fmt.Fprintf(w, "%s \033[32m🟢 Ready\033[0m (revision: %s)\n", resource.ObjectName(), resource.Revision)
+			case resource.Status == "Unknown":
+				fmt.Fprintf(w, "%s \033[33m⚠️  Unknown\033[0m - %s\n", resource.ObjectName(), resource.Message)
+			default:
+				fmt.Fprintf(w, "%s \033[31m🔴 Not Ready\033[0m - %s\n", resource.ObjectName(), resource.Message)
+			}
+		}
+	}
+
+	failed := result.Failed()
+	fmt.Fprintf(w, "\n\033[1mSummary:\033[0m %d/%d resources Ready\n",
+		len(result.Findings)-len(failed), len(result.Findings))
+
+	if len(failed) > 0 {
+		fmt.Fprintf(w, "\033[31m\nFailed resources:\033[0m\n")
+		for _, resource := range failed {
+			fmt.Fprintf(w, "  - %s %s: %s\n", resource.Kind, resource.ObjectName(), resource.Message)
+		}
+	}
+
+	if len(result.Findings) == 0 {
+		fmt.Fprintf(w, "\033[33mNo Flux resources found\033[0m\n")
+	}
+}
+
+func printChecks(w io.Writer, results []checker.Result) {
+	// the endpoint is only shown if the checks were answered by different endpoints
+	endpoints := map[string]bool{}
+	for _, result := range results {
+		if result.Endpoint != "" {
+			endpoints[result.Endpoint] = true
+		}
+	}
+
+	target := ""
+	for _, result := range results {
+		if result.Target != "" && result.Target != target {
+			target = result.Target
+			if result.TargetSource != "" {
+				fmt.Fprintf(w, "\033[36mclustercheck \033[0m on %s (cluster label from %s)\n", target, result.TargetSource)
+			} else {
+				fmt.Fprintf(w, "\033[36mclustercheck \033[0m on %s\n", target)
+			}
+		}
+
+		value := ""
+		if result.Value != "" {
+			value = fmt.Sprintf(" (%s)", result.Value)
+		}
+		if result.Passed && result.Attempts > 1 {
+			value += fmt.Sprintf(" after %d attempts", result.Attempts)
+		}
+		if len(endpoints) > 1 && result.Endpoint != "" {
+			value += " via " + result.Endpoint
+		}
+
+		if result.Passed {
+			fmt.Fprintf(w, "%s \033[32m🟢 OK%s\033[0m\n", result.Name, value)
+		} else if result.TimedOut {
+			fmt.Fprintf(w, "%s \033[35m⏱️  TIMEOUT\033[0m - %s\n", result.Name, result.Message)
+		} else if result.Warning() {
+			fmt.Fprintf(w, "%s \033[33m🟡 WARN%s\033[0m - %s\n", result.Name, value, result.Message)
+		} else {
+			fmt.Fprintf(w, "%s \033[31m🔴 FAIL%s\033[0m - %s\n", result.Name, value, result.Message)
+		}
+
+		for _, finding := range result.Failed() {
+			fmt.Fprintf(w, "  - %s (%s)\n", finding.ObjectName(), finding.Status)
+		}
+	}
+}
+
+func printSummary(w io.Writer, res *gatecheck.GateCheckResult) {
+	printBox(w, "GATE CHECK SUMMARY")
+
+	if res.OverallPassed {
+		fmt.Fprintf(w, "\033[1;32m✓ CLUSTER HEALTH: PASSED\033[0m\n")
+	} else {
+		fmt.Fprintf(w, "\033[1;31m✗ CLUSTER HEALTH: FAILED\033[0m\n")
+	}
+
+	fmt.Fprintf(w, "\n\033[1mHealth Score: %.1f%% (%d of %d checks passed)\033[0m\n",
+		res.HealthScore, res.PassedChecks, res.TotalChecks)
+	if res.WarningChecks > 0 {
+		fmt.Fprintf(w, "\033[33m%d warnings not counted in the health score\033[0m\n", res.WarningChecks)
+	}
+	if res.TimedOutChecks > 0 {
+		fmt.Fprintf(w, "\033[35m%d checks timed out\033[0m\n", res.TimedOutChecks)
+	}
+	fmt.Fprintln(w)
+
+	// Detailed Results
+	fmt.Fprintln(w, "Detailed Results:")
+	fmt.Fprintln(w, "─────────────────────────────────────────────────")
+	for _, check := range res.CheckResults {
+		if check.Passed {
+			fmt.Fprintf(w, "✓ \033[32m%-30s\033[0m PASS\n", check.Name)
+		} else if check.TimedOut {
+			fmt.Fprintf(w, "⏱ \033[35m%-30s\033[0m TIMEOUT - %s\n", check.Name, check.Message)
+		} else if check.Warning() {
+			fmt.Fprintf(w, "⚠ \033[33m%-30s\033[0m WARN - %s\n", check.Name, check.Message)
+		} else {
+			fmt.Fprintf(w, "✗ \033[31m%-30s\033[0m FAIL - %s\n", check.Name, check.Message)
+		}
+	}
+	fmt.Fprintln(w)
+
+	// Quality Gate Decision
+	fmt.Fprintln(w, "Quality Gate Decision:")
+	fmt.Fprintln(w, "─────────────────────────────────────────────────")
+	tier, decision := res.QualityGate()
+	switch tier {
+	case "EXCELLENT":
+		fmt.Fprintf(w, "\033[1;32m🟢 %s - %s\033[0m\n", tier, decision)
+	case "GOOD":
+		fmt.Fprintf(w, "\033[1;32m🟡 %s - %s\033[0m\n", tier, decision)
+	case "FAIR":
+		fmt.Fprintf(w, "\033[1;33m🟠 %s - %s\033[0m\n", tier, decision)
+	default:
+		fmt.Fprintf(w, "\033[1;31m🔴 %s - %s\033[0m\n", tier, decision)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,150 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/eumel8/clustercheck/pkg/checker"
+	"github.com/eumel8/clustercheck/pkg/gatecheck"
+)
+
+// testResult returns a gate check result with one check of each built-in category
+func testResult() *gatecheck.GateCheckResult {
+	return &gatecheck.GateCheckResult{
+		Context:      "test-context",
+		TotalChecks:  3,
+		PassedChecks: 1,
+		FailedChecks: 2,
+		HealthScore:  33.3,
+		CheckResults: []gatecheck.CheckResult{
+			{
+				Name:     "Pod Health",
+				Category: checker.CategoryPods,
+				Message:  "1 pods not in Running or Succeeded state",
+				Findings: []checker.Finding{
+					{Kind: "Pod", Namespace: "default", Name: "app", Healthy: true, Status: "Running"},
+					{Kind: "Pod", Namespace: "default", Name: "broken", Status: "Pending", Reason: "ImagePullBackOff"},
+				},
+			},
+			{
+				Name:     "Flux Resources",
+				Category: checker.CategoryFlux,
+				Passed:   true,
+				Message:  "All HelmReleases and Kustomizations are Ready",
+				Findings: []checker.Finding{
+					{Kind: "HelmRelease", Namespace: "flux-system", Name: "app", Healthy: true, Status: "Ready", Revision: "1.0.0"},
+				},
+			},
+			{
+				Name:         "APISERVER",
+				Category:     checker.CategoryPrometheus,
+				Target:       "test-cluster",
+				TargetSource: "cluster map",
+				Query:        `avg(up{job="kube-apiserver"})`,
+				Value:        "0",
+				Endpoint:     "https://prometheus.example.com",
+				Message:      "Value: 0 (expected: 1)",
+			},
+		},
+	}
+}
+
+func TestText(t *testing.T) {
+	var buf bytes.Buffer
+	Text(&buf, testResult(), false)
+	output := buf.String()
+
+	expected := []string{
+		"podcheck \033[0m on test-context",
+		"default/broken \033[31m🔴 Pending",
+		"Summary: 1/2 pods in Running or Succeeded state",
+		"  - default/broken (Pending: ImagePullBackOff)",
+		"flux-system/app \033[32m🟢 Ready\033[0m (revision: 1.0.0)",
+		"clustercheck \033[0m on test-cluster (cluster label from cluster map)",
+		"APISERVER \033[31m🔴 FAIL (0)\033[0m - Value: 0 (expected: 1)",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected output to contain %q, got:\n%s", e, output)
+		}
+	}
+
+	if strings.Contains(output, "GATE CHECK SUMMARY") {
+		t.Error("Expected no gate summary without gate mode")
+	}
+}
+
+func TestTextGate(t *testing.T) {
+	var buf bytes.Buffer
+	Text(&buf, testResult(), true)
+	output := buf.String()
+
+	expected := []string{
+		"CLUSTER GATE CHECK - test-context",
+		"[1/3] Pod Health Check",
+		"[3/3] Prometheus Monitoring Check",
+		"GATE CHECK SUMMARY",
+		"Health Score: 33.3% (1 of 3 checks passed)",
+		"POOR - Not ready for production",
+	}
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			t.Errorf("Expected output to contain %q, got:\n%s", e, output)
+		}
+	}
+}
+
+func TestTextSeries(t *testing.T) {
+	res := &gatecheck.GateCheckResult{
+		Context: "test-context",
+		CheckResults: []gatecheck.CheckResult{
+			{
+				Name:     "NODE",
+				Category: checker.CategoryPrometheus,
+				Target:   "test-cluster",
+				Value:    "0",
+				Message:  "1 of 2 series failed (expected: 1)",
+				Findings: []checker.Finding{
+					{Kind: "Series", Name: `{node="node-1"}`, Healthy: true, Status: "1"},
+					{Kind: "Series", Name: `{node="node-2"}`, Healthy: false, Status: "0"},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	Text(&buf, res, false)
+	output := buf.String()
+
+	if !strings.Contains(output, `  - {node="node-2"} (0)`) {
+		t.Errorf("Expected output to list the failed series, got:\n%s", output)
+	}
+	if strings.Contains(output, "node-1") {
+		t.Errorf("Expected output not to list healthy series, got:\n%s", output)
+	}
+}
+
+func TestTextEndpoints(t *testing.T) {
+	res := &gatecheck.GateCheckResult{
+		Context: "test-context",
+		CheckResults: []gatecheck.CheckResult{
+			{Name: "APISERVER", Category: checker.CategoryPrometheus, Target: "test-cluster", Passed: true, Value: "1", Endpoint: "https://prometheus-1"},
+			{Name: "KUBEDNS", Category: checker.CategoryPrometheus, Target: "test-cluster", Passed: true, Value: "1", Endpoint: "https://prometheus-0"},
+		},
+	}
+
+	var buf bytes.Buffer
+	Text(&buf, res, false)
+	if !strings.Contains(buf.String(), "APISERVER \033[32m🟢 OK (1) via https://prometheus-1") {
+		t.Errorf("Expected output to show the endpoints after a failover, got:\n%s", buf.String())
+	}
+
+	// a single endpoint is not shown
+	res.CheckResults[1].Endpoint = "https://prometheus-1"
+	buf.Reset()
+	Text(&buf, res, false)
+	if strings.Contains(buf.String(), "via") {
+		t.Errorf("Expected output not to show a single endpoint, got:\n%s", buf.String())
+	}
+}